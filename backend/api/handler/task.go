@@ -90,6 +90,14 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 	})
 }
 
+// ListTransitions 获取支持的转场效果列表
+// GET /api/transitions
+func (h *TaskHandler) ListTransitions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"transitions": service.SupportedTransitions(),
+	})
+}
+
 // WebSocket升级器
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
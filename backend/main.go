@@ -107,6 +107,7 @@ func main() {
 		api.GET("/tasks", taskHandler.ListTasks)
 		api.GET("/tasks/:id", taskHandler.GetTask)
 		api.GET("/tasks/:id/progress", taskHandler.WatchProgress) // WebSocket
+		api.GET("/transitions", taskHandler.ListTransitions)
 	}
 
 	// 上传接口
@@ -183,7 +184,7 @@ func main() {
 	}()
 
 	// 接收关闭信号
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	fmt.Println("Shutdown Server ...")
@@ -17,30 +17,32 @@ const (
 
 // Task 任务模型 - 核心差异点：完整记录执行信息
 type Task struct {
-	ID            string          `json:"id" xorm:"not null text 'id'" gorm:"id"`
-	Type          string          `json:"type" xorm:"text 'type'"`
-	Status        TaskStatus      `json:"status" xorm:"text 'status'"`
-	Progress      float64         `json:"progress" xorm:"numeric 'progress'"`
-	CurrentFrame  int             `json:"current_frame" xorm:"int8 'current_frame'"`
-	TotalFrames   int             `json:"total_frames" xorm:"int8 'total_frames'"`
-	Eta           int             `json:"eta" xorm:"int8 'eta'"`
-	InputParams   TaskInputParams `json:"input_params" xorm:"jsonb 'input_params'" gorm:"serializer:json"`
-	FfmpegCommand string          `json:"ffmpeg_command" xorm:"text 'ffmpeg_command'"` // 完整的ffmpeg命令
-	FilterGraph   string          `json:"filter_graph" xorm:"text 'filter_graph'"`     // filter_complex图
-	StderrLog     string          `json:"stderr_log" xorm:"text 'stderr_log'"`
-	ErrorMessage  string          `json:"error_message" xorm:"text 'error_message'"` // 错误摘要
-	OutputFile    string          `json:"output_file" xorm:"text 'output_file'"`
-	OutputUrl     string          `json:"output_url" xorm:"text 'output_url'"`
-	CreatedAt     time.Time       `json:"created_at" xorm:"timestamptz 'created_at'"`
-	UpdatedAt     time.Time       `json:"updated_at" xorm:"timestamptz 'updated_at'"`
-	DeletedAt     gorm.DeletedAt  `json:"-" xorm:"timestamptz 'deleted_at'" gorm:"index"`
+	ID            string            `json:"id" xorm:"not null text 'id'" gorm:"id"`
+	Type          string            `json:"type" xorm:"text 'type'"`
+	Status        TaskStatus        `json:"status" xorm:"text 'status'"`
+	Progress      float64           `json:"progress" xorm:"numeric 'progress'"`
+	CurrentFrame  int               `json:"current_frame" xorm:"int8 'current_frame'"`
+	TotalFrames   int               `json:"total_frames" xorm:"int8 'total_frames'"`
+	Eta           int               `json:"eta" xorm:"int8 'eta'"`
+	InputParams   TaskInputParams   `json:"input_params" xorm:"jsonb 'input_params'" gorm:"serializer:json"`
+	FfmpegCommand string            `json:"ffmpeg_command" xorm:"text 'ffmpeg_command'"` // 完整的ffmpeg命令
+	FilterGraph   string            `json:"filter_graph" xorm:"text 'filter_graph'"`     // filter_complex图
+	StderrLog     string            `json:"stderr_log" xorm:"text 'stderr_log'"`
+	ErrorMessage  string            `json:"error_message" xorm:"text 'error_message'"` // 错误摘要
+	OutputFile    string            `json:"output_file" xorm:"text 'output_file'"`
+	OutputUrl     string            `json:"output_url" xorm:"text 'output_url'"`
+	Artifacts     map[string]string `json:"artifacts" xorm:"jsonb 'artifacts'" gorm:"serializer:json"`         // 附加产物（封面、雪碧图等）名称 -> URL
+	Loudness      *LoudnessReport   `json:"loudness,omitempty" xorm:"jsonb 'loudness'" gorm:"serializer:json"` // 响度标准化的测量值和结果
+	CreatedAt     time.Time         `json:"created_at" xorm:"timestamptz 'created_at'"`
+	UpdatedAt     time.Time         `json:"updated_at" xorm:"timestamptz 'updated_at'"`
+	DeletedAt     gorm.DeletedAt    `json:"-" xorm:"timestamptz 'deleted_at'" gorm:"index"`
 }
 
 // TaskInputParams 输入参数（语义化设计）
 // 使用灵活的结构支持多种任务类型
 type TaskInputParams struct {
 	// 通用参数
-	OutputFormat string `json:"output_format"` // 输出格式：mp4, mov等
+	OutputFormat string `json:"output_format"` // 输出格式：mp4, mov, mkv, webm, hls, dash等
 	VideoCodec   string `json:"video_codec"`   // 视频编码：libx264, libx265
 	AudioCodec   string `json:"audio_codec"`   // 音频编码：aac, mp3
 	Width        int    `json:"width"`         // 视频宽度
@@ -48,18 +50,340 @@ type TaskInputParams struct {
 	FPS          int    `json:"fps"`           // 帧率
 	VideoBitrate string `json:"video_bitrate"` // 视频码率：1M, 2M
 	AudioBitrate string `json:"audio_bitrate"` // 音频码率：128k, 192k
+	SampleRate   int    `json:"sample_rate"`   // 音频采样率：44100, 48000，0表示保持源采样率
+	Channels     int    `json:"channels"`      // 音频声道数：1, 2，0表示保持源声道数
 
 	// 单图片+音频任务参数
 	ImagePath string `json:"image_path"` // 单张图片路径
 	AudioPath string `json:"audio_path"` // 音频路径
 	AudioLoop bool   `json:"audio_loop"` // 是否循环播放音频
 
+	// 音频可视化（音频图/audiogram），叠加在图片上
+	Visualizer *VisualizerOptions `json:"visualizer,omitempty"`
+
 	// 多图片轮播任务参数
-	ImagePaths      []string `json:"image_paths"`      // 多张图片路径列表
-	ImageDuration   float64  `json:"image_duration"`   // 每张图片显示时长（秒）
-	TransitionType  string   `json:"transition_type"`  // 转场效果：fade, slide, none
-	TransitionDur   float64  `json:"transition_dur"`   // 转场持续时间（秒）
-	BackgroundAudio string   `json:"background_audio"` // 背景音乐路径
+	Slides              []Slide    `json:"slides"`                  // 幻灯片列表（逐张设置时长、字幕、转场），设置后忽略image_paths
+	CaptionStyle        *TextStyle `json:"caption_style,omitempty"` // 幻灯片字幕的默认样式
+	ImagePaths          []string   `json:"image_paths"`             // 多张图片路径列表（slides的简写）
+	ImageDuration       float64    `json:"image_duration"`          // 每张图片显示时长（秒）
+	TransitionType      string     `json:"transition_type"`         // 转场效果：xfade转场名称（fade, wipeleft, slideright, circleopen, dissolve等，见GET /api/transitions）或none
+	Transitions         []string   `json:"transitions"`             // 每个衔接处的转场（第i项为第i张到第i+1张），为空的项使用transition_type
+	TransitionDur       float64    `json:"transition_dur"`          // 转场持续时间（秒）
+	BackgroundAudio     string     `json:"background_audio"`        // 背景音乐路径
+	BackgroundAudioLoop bool       `json:"background_audio_loop"`   // 背景音乐短于幻灯片时循环播放
+	BackgroundVolume    float64    `json:"background_volume"`       // 背景音乐音量（0-1），默认1
+	AudioFadeIn         float64    `json:"audio_fade_in"`           // 背景音乐淡入时长（秒）
+	AudioFadeOut        float64    `json:"audio_fade_out"`          // 背景音乐淡出时长（秒），在视频结尾处淡出
+	Voiceover           string     `json:"voiceover"`               // 旁白音频路径，旁白出现时背景音乐自动压低
+	DuckingRatio        float64    `json:"ducking_ratio"`           // 旁白闪避的压缩比（1-20），默认8
+	Motion              string     `json:"motion"`                  // 运动效果（Ken Burns）：none, zoom_in, zoom_out, pan_left, pan_right, pan_up, pan_down, random
+	MotionZoom          float64    `json:"motion_zoom"`             // 运动效果的最大缩放倍数，默认1.2
+	ImageMotions        []string   `json:"image_motions"`           // 每张图片的运动效果（按image_paths顺序），为空的项使用motion
+
+	// 视频转码任务参数
+	InputPath string `json:"input_path"` // 输入视频路径（audio_convert任务可为音频或视频）
+
+	// 视频裁剪任务参数（clip_ranges为空时使用clip_start/clip_end/clip_duration单段裁剪）
+	ClipStart    float64     `json:"clip_start"`    // 起始时间（秒）
+	ClipEnd      float64     `json:"clip_end"`      // 结束时间（秒），为0表示到结尾
+	ClipDuration float64     `json:"clip_duration"` // 裁剪时长（秒），优先于clip_end
+	ClipRanges   []ClipRange `json:"clip_ranges"`   // 多段裁剪，按顺序拼接为一个输出
+	ClipMode     string      `json:"clip_mode"`     // 裁剪模式：fast（流复制，按关键帧对齐）, accurate（重新编码，帧精确）
+
+	// 视频拼接任务参数
+	InputPaths   []string `json:"input_paths"`   // 待拼接的视频路径列表（按顺序）
+	CrossfadeDur float64  `json:"crossfade_dur"` // 片段间交叉淡化时长（秒），0表示直接拼接
+
+	// 变速任务参数（retime）
+	Retime *RetimeOptions `json:"retime,omitempty"`
+
+	// 画面调整任务参数（reframe）
+	Reframe *ReframeOptions `json:"reframe,omitempty"`
+
+	// 绿幕抠像任务参数（chromakey），input_path为前景视频
+	ChromaKey *ChromaKeyOptions `json:"chroma_key,omitempty"`
+
+	// 画面合成任务参数（画中画/多宫格）
+	Compose *ComposeOptions `json:"compose,omitempty"`
+
+	// 社交平台输出预设（设置分辨率、码率、帧率和封装限制，不能与width/height同时使用）
+	Preset *PresetOptions `json:"preset,omitempty"`
+
+	// 图片适配模式（图片类任务缩放到width x height时生效）
+	Fit *FitOptions `json:"fit,omitempty"`
+
+	// 画质修复（反交错、降噪、去色带、锐化），在缩放等几何处理之前应用于源视频
+	Restore *RestoreOptions `json:"restore,omitempty"`
+
+	// 调色（LUT、基础校色和内置风格），图片类任务和视频任务通用
+	Color *ColorOptions `json:"color,omitempty"`
+
+	// 通用视频效果（可用于所有输出视频的任务类型）
+	Watermark    *WatermarkOptions `json:"watermark,omitempty"` // 图片水印/Logo
+	Subtitle     *SubtitleOptions  `json:"subtitle,omitempty"`  // 字幕（烧录或软字幕轨道）
+	TextOverlays []TextOverlay     `json:"text_overlays"`       // 文字叠加（标题、字幕条、角标等）
+	TitleCards   []TitleCard       `json:"title_cards"`         // 片头/片尾标题卡，拼接到任务输出的开头或结尾
+	Variables    map[string]string `json:"variables"`           // 文字模板变量，文字中的{{name}}替换为对应值
+
+	// 缩略图参数：thumbnails任务的参数，或作为其他视频任务完成后的附加步骤
+	Thumbnails *ThumbnailOptions `json:"thumbnails,omitempty"`
+
+	// 响度标准化（EBU R128两遍loudnorm），作用于任务输出的音频
+	Loudness *LoudnessOptions `json:"loudness,omitempty"`
+
+	// 动图参数（gif任务）
+	GIF *GIFOptions `json:"gif,omitempty"`
+
+	// 自适应码率打包参数（output_format为hls/dash时生效）
+	HLS  *HLSOptions  `json:"hls,omitempty"`
+	DASH *DASHOptions `json:"dash,omitempty"`
+}
+
+// HLSOptions HLS自适应码率打包参数
+type HLSOptions struct {
+	SegmentDuration int         `json:"segment_duration"` // 切片时长（秒），默认6
+	Renditions      []Rendition `json:"renditions"`       // 码率阶梯，为空时使用默认1080p/720p/480p/360p
+}
+
+// DASHOptions MPEG-DASH自适应码率打包参数（fMP4/CMAF切片）
+type DASHOptions struct {
+	SegmentDuration int         `json:"segment_duration"` // 切片时长（秒），默认4
+	Renditions      []Rendition `json:"renditions"`       // 码率阶梯，为空时使用默认1080p/720p/480p/360p
+	CMAF            bool        `json:"cmaf"`             // 同时生成引用相同切片的HLS播放列表（CMAF）
+}
+
+// Rendition 自适应码率阶梯中的一档
+type Rendition struct {
+	Name         string `json:"name"`          // 名称，用于HLS子目录和DASH representation命名，如720p
+	Height       int    `json:"height"`        // 输出高度，宽度按比例计算
+	VideoBitrate string `json:"video_bitrate"` // 视频码率，如3M
+	AudioBitrate string `json:"audio_bitrate"` // 音频码率，如128k
+}
+
+// VisualizerOptions 音频可视化参数
+type VisualizerOptions struct {
+	Type     string  `json:"type"`     // 类型：waves（波形，默认）, freqs（频谱柱状图）, spectrum（滚动频谱图）, vectorscope（声像图）
+	Style    string  `json:"style"`    // 样式：waves为point/line/p2p/cline（默认），freqs为line/bar（默认）/dot，spectrum为配色方案（intensity默认、rainbow、magma等），vectorscope为lissajous（默认）/lissajous_xy/polar
+	Color    string  `json:"color"`    // 颜色（#RRGGBB），默认白色，spectrum使用style指定的配色
+	Width    float64 `json:"width"`    // 宽度占画面宽度的比例（0-1），默认0.8
+	Height   float64 `json:"height"`   // 高度占画面高度的比例（0-1），默认0.25
+	Position string  `json:"position"` // 位置：top, center, bottom（默认）水平居中；top_left, top_right, bottom_left, bottom_right
+	Margin   int     `json:"margin"`   // 距画面边缘的像素距离，默认40
+	Opacity  float64 `json:"opacity"`  // 不透明度（0-1），默认1
+}
+
+// RetimeOptions 变速/倒放参数
+type RetimeOptions struct {
+	Speed       float64 `json:"speed"`       // 变速倍数（0.25-4），小于1为慢放，默认1
+	Reverse     bool    `json:"reverse"`     // 倒放（音视频同时倒放），仅支持60秒以内的片段
+	Interpolate bool    `json:"interpolate"` // 慢放时使用minterpolate运动补偿插帧（较慢）
+}
+
+// ReframeOptions 裁剪/旋转/翻转参数，按裁剪 -> 旋转 -> 翻转的顺序处理
+// 源视频的旋转元数据会先被校正，crop坐标以校正后的显示画面为准
+type ReframeOptions struct {
+	Crop      *CropRect `json:"crop,omitempty"` // 手动裁剪区域
+	AutoCrop  bool      `json:"auto_crop"`      // 自动检测并去除黑边（先执行cropdetect分析），不能与crop同时使用
+	CropLimit int       `json:"crop_limit"`     // 自动裁剪的黑边亮度阈值（0-255），默认24
+	Rotate    int       `json:"rotate"`         // 顺时针旋转角度：0, 90, 180, 270
+	FlipH     bool      `json:"flip_h"`         // 水平翻转
+	FlipV     bool      `json:"flip_v"`         // 垂直翻转
+}
+
+// CropRect 裁剪区域（像素）
+type CropRect struct {
+	X      int `json:"x"`      // 左上角横坐标
+	Y      int `json:"y"`      // 左上角纵坐标
+	Width  int `json:"width"`  // 宽度
+	Height int `json:"height"` // 高度
+}
+
+// PresetOptions 社交平台输出预设
+type PresetOptions struct {
+	Name    string `json:"name"`    // 预设名称：tiktok_9x16, instagram_square, instagram_4x5, youtube_1080p
+	Reframe string `json:"reframe"` // 画面重构方式：crop（居中裁剪，默认）, blur（完整显示+模糊背景）, track（跟随运动区域裁剪，仅transcode/watermark任务）
+}
+
+// RestoreOptions 画质修复参数，按反交错 -> 降噪 -> 去色带 -> 锐化的顺序应用
+type RestoreOptions struct {
+	Deinterlace     string  `json:"deinterlace"`      // 反交错：auto（先用idet检测，隔行时使用bwdif）, yadif, bwdif，为空不处理
+	Denoise         string  `json:"denoise"`          // 降噪：hqdn3d（快速）, nlmeans（效果好但很慢），为空不处理
+	DenoiseStrength float64 `json:"denoise_strength"` // 降噪强度（0-1），默认0.5
+	Deband          bool    `json:"deband"`           // 去除色带（渐变区域的条纹）
+	Sharpen         float64 `json:"sharpen"`          // 锐化强度（0-2），0表示不锐化
+}
+
+// ColorOptions 调色参数，按内置风格 -> LUT -> 基础校色的顺序应用
+type ColorOptions struct {
+	Look       string  `json:"look"`       // 内置风格：warm, cool, vintage, cinematic, vivid, bw
+	LUTPath    string  `json:"lut_path"`   // 3D LUT文件路径（.cube或.3dl）
+	Brightness float64 `json:"brightness"` // 亮度（-1到1），默认0
+	Contrast   float64 `json:"contrast"`   // 对比度（0到3），0表示不调整（1为原始）
+	Saturation float64 `json:"saturation"` // 饱和度（0到3），0表示不调整（1为原始），黑白请使用bw风格
+	Gamma      float64 `json:"gamma"`      // 伽马（0.1到10），0表示不调整（1为原始）
+}
+
+// ChromaKeyOptions 绿幕抠像参数，输出尺寸使用width/height，未设置时与背景一致
+type ChromaKeyOptions struct {
+	Background  string  `json:"background"`   // 背景图片或视频路径，背景视频短于前景时循环播放
+	Color       string  `json:"color"`        // 抠除的颜色（#RRGGBB），默认#00FF00
+	Similarity  float64 `json:"similarity"`   // 颜色相似度阈值（0.01-1），默认0.1
+	Blend       float64 `json:"blend"`        // 边缘混合（0-1），默认0.05
+	Despill     float64 `json:"despill"`      // 溢色抑制强度（0-1），0表示不处理
+	Position    string  `json:"position"`     // 前景位置：top, center, bottom（默认）水平居中；top_left, top_right, bottom_left, bottom_right
+	Margin      int     `json:"margin"`       // 前景距画面边缘的像素距离
+	Scale       float64 `json:"scale"`        // 前景宽度占画面宽度的比例（0-1），默认1
+	AudioSource string  `json:"audio_source"` // 音频来源：foreground（默认）, background, mix, none
+}
+
+// ComposeOptions 画面合成参数，输出尺寸使用width/height（默认1280x720）
+type ComposeOptions struct {
+	Layout        string         `json:"layout"`                   // 布局：pip（画中画，默认）, grid（多宫格）
+	Inputs        []ComposeInput `json:"inputs"`                   // 输入视频/图片（2-9个），pip布局中第一个为主画面
+	Columns       int            `json:"columns"`                  // grid列数，默认按输入数量自动计算
+	Rows          int            `json:"rows"`                     // grid行数，默认按输入数量和列数计算
+	Gap           int            `json:"gap"`                      // grid格子间距（像素）
+	Background    string         `json:"background"`               // 背景/空白格子颜色（#RRGGBB），默认黑色
+	Margin        int            `json:"margin"`                   // pip小窗距画面边缘的像素距离，默认20
+	Border        int            `json:"border"`                   // pip小窗边框宽度（像素）
+	BorderColor   string         `json:"border_color"`             // pip小窗边框颜色（#RRGGBB），默认白色
+	Radius        int            `json:"radius"`                   // pip小窗圆角半径（像素）
+	AudioInput    int            `json:"audio_input"`              // 提供音频的输入索引，默认0
+	AudioMix      bool           `json:"audio_mix"`                // 混合所有输入的音频，优先于audio_input
+	DurationInput *int           `json:"duration_input,omitempty"` // 以该输入的时长为输出时长，为空时取最长的输入
+}
+
+// ComposeInput 画面合成的一个输入
+type ComposeInput struct {
+	Path     string  `json:"path"`     // 视频或图片路径
+	Position string  `json:"position"` // pip小窗位置：top_left, top_right, bottom_left, bottom_right（默认）, center
+	Scale    float64 `json:"scale"`    // pip小窗宽度占画面宽度的比例（0-1），默认0.3
+}
+
+// FitOptions 画面适配参数
+type FitOptions struct {
+	Mode       string  `json:"mode"`       // 模式：stretch（拉伸，默认）, contain（完整显示并填充背景）, cover（铺满并裁剪）
+	Background string  `json:"background"` // contain模式的背景：#RRGGBB纯色（默认黑色）或blur（模糊的原图）
+	FocalX     float64 `json:"focal_x"`    // cover模式的焦点水平位置（0-1），0表示居中
+	FocalY     float64 `json:"focal_y"`    // cover模式的焦点垂直位置（0-1），0表示居中
+}
+
+// Slide 幻灯片中的一张图片
+type Slide struct {
+	ImagePath    string     `json:"image_path"`              // 图片路径
+	Duration     float64    `json:"duration"`                // 显示时长（秒），0表示使用image_duration
+	Caption      string     `json:"caption"`                 // 字幕文字，为空不显示
+	CaptionStyle *TextStyle `json:"caption_style,omitempty"` // 字幕样式，为空使用全局caption_style
+	Transition   string     `json:"transition"`              // 到下一张的转场，为空使用transitions/transition_type
+	Motion       string     `json:"motion"`                  // 运动效果，为空使用image_motions/motion
+}
+
+// TextStyle 文字样式（drawtext）
+type TextStyle struct {
+	Position   string  `json:"position"`    // 位置：top, center, bottom（默认）水平居中；top_left, top_right, bottom_left, bottom_right
+	FontName   string  `json:"font_name"`   // 字体名称（fontconfig），为空使用系统默认字体
+	FontSize   int     `json:"font_size"`   // 字号，默认为画面高度的1/18
+	FontColor  string  `json:"font_color"`  // 文字颜色（#RRGGBB），默认白色
+	BoxColor   string  `json:"box_color"`   // 背景框颜色（#RRGGBB），为空不绘制背景框
+	BoxOpacity float64 `json:"box_opacity"` // 背景框不透明度（0-1），默认0.5
+	Margin     int     `json:"margin"`      // 距画面边缘的像素距离，默认40
+}
+
+// TextOverlay 文字叠加
+type TextOverlay struct {
+	Text string `json:"text"` // 文字内容，支持{{变量}}
+	TextStyle
+	StartTime float64 `json:"start_time"` // 开始显示时间（秒）
+	EndTime   float64 `json:"end_time"`   // 结束显示时间（秒），0表示到结尾
+	FadeIn    float64 `json:"fade_in"`    // 淡入时长（秒）
+	FadeOut   float64 `json:"fade_out"`   // 淡出时长（秒），需要设置end_time
+}
+
+// TitleCard 标题卡：以纯色或图片为背景的独立片段
+type TitleCard struct {
+	Placement     string     `json:"placement"`                // 位置：start（片头，默认）, end（片尾）
+	Duration      float64    `json:"duration"`                 // 时长（秒），默认3
+	Title         string     `json:"title"`                    // 标题，支持{{变量}}
+	Subtitle      string     `json:"subtitle"`                 // 副标题，支持{{变量}}
+	Background    string     `json:"background"`               // 背景颜色（#RRGGBB），默认黑色
+	ImagePath     string     `json:"image_path"`               // 背景图片，设置后优先于background
+	TitleStyle    *TextStyle `json:"title_style,omitempty"`    // 标题样式，默认居中
+	SubtitleStyle *TextStyle `json:"subtitle_style,omitempty"` // 副标题样式，默认底部
+	Fade          float64    `json:"fade"`                     // 淡入淡出时长（秒），默认0.5
+}
+
+// LoudnessOptions 响度标准化参数（EBU R128）
+type LoudnessOptions struct {
+	TargetI  float64 `json:"target_i"`  // 目标综合响度（LUFS，-70到-5），默认-16
+	TruePeak float64 `json:"true_peak"` // 真峰值上限（dBTP，-9到0），默认-1.5
+	LRA      float64 `json:"lra"`       // 目标响度范围（LU，1到50），默认11
+}
+
+// LoudnessReport 响度标准化报告，用于审计是否达标
+type LoudnessReport struct {
+	TargetI           float64 `json:"target_i"`
+	TargetTP          float64 `json:"target_tp"`
+	TargetLRA         float64 `json:"target_lra"`
+	MeasuredI         float64 `json:"measured_i"`         // 源音频综合响度（LUFS）
+	MeasuredTP        float64 `json:"measured_tp"`        // 源音频真峰值（dBTP）
+	MeasuredLRA       float64 `json:"measured_lra"`       // 源音频响度范围（LU）
+	MeasuredThresh    float64 `json:"measured_thresh"`    // 源音频门限（LUFS）
+	OutputI           float64 `json:"output_i"`           // 输出综合响度（LUFS）
+	OutputTP          float64 `json:"output_tp"`          // 输出真峰值（dBTP）
+	OutputLRA         float64 `json:"output_lra"`         // 输出响度范围（LU）
+	NormalizationType string  `json:"normalization_type"` // linear（线性增益）或dynamic（动态压缩）
+}
+
+// GIFOptions 动图（GIF/动态WebP）参数，宽度和帧率使用通用的width/fps参数
+type GIFOptions struct {
+	StartTime  float64 `json:"start_time"`  // 起始时间（秒）
+	Duration   float64 `json:"duration"`    // 时长（秒），0表示到结尾
+	Dither     string  `json:"dither"`      // 抖动算法：bayer, floyd_steinberg, sierra2_4a, none，默认sierra2_4a
+	BayerScale int     `json:"bayer_scale"` // bayer抖动强度（0-5），仅bayer生效
+	MaxColors  int     `json:"max_colors"`  // 调色板颜色数（2-256），默认256
+	StatsMode  string  `json:"stats_mode"`  // 调色板统计模式：full, diff（优化运动部分），默认full
+	Loop       int     `json:"loop"`        // 循环次数：0无限循环，-1不循环，N为重复N次
+	Quality    int     `json:"quality"`     // WebP质量（0-100），默认75
+}
+
+// ThumbnailOptions 缩略图参数：封面图、雪碧图和WebVTT预览索引
+type ThumbnailOptions struct {
+	Interval   float64 `json:"interval"`    // 雪碧图取帧间隔（秒），默认10
+	Width      int     `json:"width"`       // 单个缩略图宽度，默认160，高度按比例计算
+	Columns    int     `json:"columns"`     // 每张雪碧图的列数，默认5
+	Rows       int     `json:"rows"`        // 每张雪碧图的行数，默认5
+	PosterTime float64 `json:"poster_time"` // 封面截取时间（秒），默认为视频时长的10%
+}
+
+// SubtitleOptions 字幕参数，字幕时间轴以输出视频为准
+type SubtitleOptions struct {
+	Path         string `json:"path"`          // 字幕文件路径：SRT、ASS或WebVTT
+	Mode         string `json:"mode"`          // 模式：burn（烧录到画面，默认）, soft（作为可选字幕轨道封装）
+	Language     string `json:"language"`      // 字幕轨道语言（ISO 639-2，如chi、eng），仅soft模式
+	FontName     string `json:"font_name"`     // 字体名称，仅burn模式
+	FontSize     int    `json:"font_size"`     // 字号，仅burn模式
+	PrimaryColor string `json:"primary_color"` // 文字颜色（#RRGGBB），仅burn模式
+	OutlineColor string `json:"outline_color"` // 描边颜色（#RRGGBB），仅burn模式
+	Outline      int    `json:"outline"`       // 描边宽度，仅burn模式
+	MarginV      int    `json:"margin_v"`      // 距底部的垂直边距，仅burn模式
+}
+
+// WatermarkOptions 图片水印参数
+type WatermarkOptions struct {
+	ImagePath string  `json:"image_path"` // 水印图片路径
+	Position  string  `json:"position"`   // 锚点位置：top_left, top_right, bottom_left, bottom_right, center
+	Margin    int     `json:"margin"`     // 距边缘的像素距离
+	Scale     float64 `json:"scale"`      // 水印宽度占视频宽度的比例（0-1），0表示保持原始尺寸
+	Opacity   float64 `json:"opacity"`    // 不透明度（0-1），未设置时为1（完全不透明）
+	StartTime float64 `json:"start_time"` // 开始显示时间（秒）
+	EndTime   float64 `json:"end_time"`   // 结束显示时间（秒），0表示持续到结尾
+}
+
+// ClipRange 裁剪时间段
+type ClipRange struct {
+	Start    float64 `json:"start"`    // 起始时间（秒）
+	End      float64 `json:"end"`      // 结束时间（秒），为0表示到结尾
+	Duration float64 `json:"duration"` // 时长（秒），优先于end
 }
 
 // TaskProgress WebSocket实时进度推送
@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// CropArea 裁剪区域（像素）
+type CropArea struct {
+	Width  int
+	Height int
+	X      int
+	Y      int
+}
+
+// cropDetectPattern 匹配cropdetect日志中的建议裁剪参数
+// 日志示例: [Parsed_cropdetect_0 @ 0x...] x1:0 x2:1919 y1:140 y2:939 w:1920 h:800 x:0 y:140 pts:... t:... crop=1920:800:0:140
+var cropDetectPattern = regexp.MustCompile(`\[Parsed_cropdetect.*crop=(-?\d+):(-?\d+):(-?\d+):(-?\d+)`)
+
+// DetectCrop 执行cropdetect分析遍：从start开始分析duration秒的视频，不产生输出
+// limit为黑边亮度阈值（0-255）；reset=0时cropdetect累积所有帧的有效区域，最后一行即整段的建议裁剪
+func (p *Parser) DetectCrop(filePath string, start, duration float64, limit int) (*CropArea, error) {
+	cmd := exec.Command(p.binaryPath,
+		"-hide_banner",
+		"-nostats",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-i", filePath,
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-map", "0:v:0",
+		"-vf", fmt.Sprintf("cropdetect=limit=%d:round=2:reset=0", limit),
+		"-f", "null",
+		"-",
+	)
+
+	// cropdetect的结果输出在stderr
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("cropdetect analysis failed: %w, output: %s", err, truncate(string(output), 500))
+	}
+
+	return ParseCropDetect(string(output))
+}
+
+// ParseCropDetect 从ffmpeg日志中解析最后一条cropdetect建议裁剪
+func ParseCropDetect(stderrLog string) (*CropArea, error) {
+	matches := cropDetectPattern.FindAllStringSubmatch(stderrLog, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cropdetect result not found in ffmpeg output")
+	}
+
+	last := matches[len(matches)-1]
+	area := &CropArea{}
+	area.Width, _ = strconv.Atoi(last[1])
+	area.Height, _ = strconv.Atoi(last[2])
+	area.X, _ = strconv.Atoi(last[3])
+	area.Y, _ = strconv.Atoi(last[4])
+
+	if area.Width <= 0 || area.Height <= 0 {
+		// 整段画面全黑时cropdetect给出负数尺寸
+		return nil, fmt.Errorf("cropdetect found no picture area (video may be black)")
+	}
+	return area, nil
+}
@@ -0,0 +1,56 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCropDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		want    CropArea
+		wantErr string // 期望的错误信息片段，为空表示不应出错
+	}{
+		{
+			name: "letterbox",
+			log:  "[Parsed_cropdetect_0 @ 0x55d5] x1:0 x2:1919 y1:140 y2:939 w:1920 h:800 x:0 y:140 pts:1001 t:0.041708 limit:0.094118 crop=1920:800:0:140\n",
+			want: CropArea{Width: 1920, Height: 800, X: 0, Y: 140},
+		},
+		{
+			name: "last line wins",
+			log: "[Parsed_cropdetect_0 @ 0x55d5] x1:0 x2:1919 y1:0 y2:1079 w:1920 h:1080 x:0 y:0 pts:1 t:0.04 crop=1920:1080:0:0\n" +
+				"frame=  100 fps=0.0 q=-0.0 size=N/A time=00:00:04.00 bitrate=N/A speed=8x\n" +
+				"[Parsed_cropdetect_0 @ 0x55d5] x1:240 x2:1679 y1:0 y2:1079 w:1440 h:1080 x:240 y:0 pts:2 t:0.08 crop=1440:1080:240:0\n",
+			want: CropArea{Width: 1440, Height: 1080, X: 240, Y: 0},
+		},
+		{
+			name:    "all black",
+			log:     "[Parsed_cropdetect_0 @ 0x55d5] x1:1919 x2:0 y1:1079 y2:0 w:-1920 h:-1080 x:1920 y:1080 pts:1 t:0.04 crop=-1920:-1080:1920:1080\n",
+			wantErr: "no picture area",
+		},
+		{
+			name:    "no result",
+			log:     "Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':\n",
+			wantErr: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCropDetect(tt.log)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseCropDetect() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCropDetect() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseCropDetect() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -52,8 +52,19 @@ func NewExecutor(binaryPath, logLevel string) *Executor {
 	}
 }
 
-// Execute 执行ffmpeg命令并实时解析进度
+// Execute 执行ffmpeg命令并实时解析进度（按帧数计算进度）
 func (e *Executor) Execute(ctx context.Context, args []string, totalFrames int, callback ProgressCallback) *ExecuteResult {
+	return e.execute(ctx, args, totalFrames, 0, callback)
+}
+
+// ExecuteWithDuration 执行ffmpeg命令并按已处理时长计算进度
+// 用于纯音频等没有视频帧的输出，进度行中没有frame=，只能依据time=计算
+func (e *Executor) ExecuteWithDuration(ctx context.Context, args []string, totalDuration float64, callback ProgressCallback) *ExecuteResult {
+	return e.execute(ctx, args, 0, totalDuration, callback)
+}
+
+// execute 执行ffmpeg命令，totalFrames和totalDuration二选一作为进度基准
+func (e *Executor) execute(ctx context.Context, args []string, totalFrames int, totalDuration float64, callback ProgressCallback) *ExecuteResult {
 	startTime := time.Now()
 	result := &ExecuteResult{
 		Command: fmt.Sprintf("%s %s", e.binaryPath, strings.Join(args, " ")),
@@ -99,7 +110,8 @@ func (e *Executor) Execute(ctx context.Context, args []string, totalFrames int,
 	var progressCount int
 	var mu sync.Mutex
 	lastOutputTime := time.Now()
-	lastProgressFrame := 0      // 上次进度更新的帧数
+	lastProgressFrame := 0         // 上次进度更新的帧数
+	lastProgressPos := 0.0         // 上次进度更新的已处理时长（秒）
 	lastProgressTime := time.Now() // 上次进度更新的时间
 
 	// 读取 stdout（防止阻塞）
@@ -203,15 +215,19 @@ func (e *Executor) Execute(ctx context.Context, args []string, totalFrames int,
 			}
 
 			// 解析进度信息
-			if progress := e.parseProgress(line, totalFrames); progress != nil {
+			if progress := e.parseProgress(line, totalFrames, totalDuration); progress != nil {
 				progressCount++
 
-				// 更新进度跟踪
+				// 更新进度跟踪：帧数或已处理时长任一增加都视为有进展
 				mu.Lock()
 				if progress.Frame > lastProgressFrame {
 					lastProgressFrame = progress.Frame
 					lastProgressTime = time.Now()
 				}
+				if pos := parseTimestamp(progress.Time); pos > lastProgressPos {
+					lastProgressPos = pos
+					lastProgressTime = time.Now()
+				}
 				mu.Unlock()
 
 				// 每 10 次进度更新输出一次日志
@@ -285,9 +301,10 @@ func truncate(s string, maxLen int) string {
 
 // parseProgress 解析FFmpeg输出的进度信息
 // FFmpeg输出示例: frame=  120 fps=30 q=28.0 size=    256kB time=00:00:04.00 bitrate= 524.3kbits/s speed=1.0x
-func (e *Executor) parseProgress(line string, totalFrames int) *Progress {
+// 纯音频输出示例: size=     512kB time=00:00:32.00 bitrate= 131.1kbits/s speed=45.2x
+func (e *Executor) parseProgress(line string, totalFrames int, totalDuration float64) *Progress {
 	// 正则匹配进度行
-	if !strings.Contains(line, "frame=") {
+	if !strings.Contains(line, "frame=") && !(totalDuration > 0 && strings.Contains(line, "size=") && strings.Contains(line, "time=")) {
 		return nil
 	}
 
@@ -335,9 +352,39 @@ func (e *Executor) parseProgress(line string, totalFrames int) *Progress {
 		}
 	}
 
+	// 按时长计算进度百分比和ETA
+	if totalDuration > 0 {
+		if pos := parseTimestamp(progress.Time); pos > 0 {
+			progress.Progress = pos / totalDuration * 100
+			if progress.Progress > 100 {
+				progress.Progress = 100
+			}
+			if progress.Speed > 0 && pos < totalDuration {
+				progress.ETA = int((totalDuration - pos) / progress.Speed)
+			}
+		}
+	}
+
 	return progress
 }
 
+// parseTimestamp 将ffmpeg的时间格式（HH:MM:SS.ms）转换为秒，无法解析时返回0
+func parseTimestamp(ts string) float64 {
+	parts := strings.Split(ts, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var seconds float64
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds
+}
+
 // extractFilterGraph 从参数中提取filter graph
 func (e *Executor) extractFilterGraph(args []string) string {
 	for i, arg := range args {
@@ -0,0 +1,45 @@
+package ffmpeg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		ts   string
+		want float64
+	}{
+		{"00:00:00.00", 0},
+		{"00:00:12.50", 12.5},
+		{"00:03:12.04", 192.04},
+		{"01:02:03.5", 3723.5},
+		{"", 0},
+		{"12.50", 0},    // 缺少时和分
+		{"N/A", 0},      // 音频刚开始时ffmpeg输出N/A
+		{"00:xx:12", 0}, // 非数字
+		{"1:2:3:4", 0},  // 段数不对
+	}
+
+	for _, tt := range tests {
+		if got := parseTimestamp(tt.ts); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestParseProgressByDuration(t *testing.T) {
+	e := &Executor{}
+	line := "size=    1024kB time=00:00:30.00 bitrate= 279.6kbits/s speed=15.0x"
+
+	progress := e.parseProgress(line, 0, 120)
+	if progress == nil {
+		t.Fatal("parseProgress() = nil")
+	}
+	if progress.Progress != 25 {
+		t.Errorf("Progress = %v, want 25", progress.Progress)
+	}
+	if progress.ETA != 6 {
+		t.Errorf("ETA = %v, want 6", progress.ETA)
+	}
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// IdetStats idet滤镜的多帧检测统计
+type IdetStats struct {
+	TFF          int // 顶场优先的帧数
+	BFF          int // 底场优先的帧数
+	Progressive  int // 逐行帧数
+	Undetermined int // 无法判断的帧数
+}
+
+// Interlaced 判断视频是否为隔行扫描：隔行帧多于逐行帧
+func (st *IdetStats) Interlaced() bool {
+	return st.TFF+st.BFF > st.Progressive
+}
+
+// Parity 获取场序：tff或bff
+func (st *IdetStats) Parity() string {
+	if st.BFF > st.TFF {
+		return "bff"
+	}
+	return "tff"
+}
+
+// idetPattern 匹配idet的多帧检测结果
+// 日志示例: [Parsed_idet_0 @ 0x...] Multi frame detection: TFF:   312 BFF:     0 Progressive:    12 Undetermined:   176
+var idetPattern = regexp.MustCompile(`Multi frame detection:\s*TFF:\s*(\d+)\s+BFF:\s*(\d+)\s+Progressive:\s*(\d+)\s+Undetermined:\s*(\d+)`)
+
+// DetectInterlace 执行idet分析遍：分析前frames帧的场序，不产生输出
+func (p *Parser) DetectInterlace(filePath string, frames int) (*IdetStats, error) {
+	cmd := exec.Command(p.binaryPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-vf", "idet",
+		"-frames:v", strconv.Itoa(frames),
+		"-f", "null",
+		"-",
+	)
+
+	// idet的统计信息在结束时输出到stderr
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("idet analysis failed: %w, output: %s", err, truncate(string(output), 500))
+	}
+
+	return ParseIdetStats(string(output))
+}
+
+// ParseIdetStats 从ffmpeg日志中解析最后一条idet多帧检测结果
+func ParseIdetStats(stderrLog string) (*IdetStats, error) {
+	matches := idetPattern.FindAllStringSubmatch(stderrLog, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("idet stats not found in ffmpeg output")
+	}
+
+	last := matches[len(matches)-1]
+	stats := &IdetStats{}
+	stats.TFF, _ = strconv.Atoi(last[1])
+	stats.BFF, _ = strconv.Atoi(last[2])
+	stats.Progressive, _ = strconv.Atoi(last[3])
+	stats.Undetermined, _ = strconv.Atoi(last[4])
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package ffmpeg
+
+import "testing"
+
+func TestParseIdetStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		want       IdetStats
+		interlaced bool
+		parity     string
+	}{
+		{
+			name: "top field first",
+			log: "[Parsed_idet_0 @ 0x5581] Repeated Fields: Neither:   499 Top:     1 Bottom:     0\n" +
+				"[Parsed_idet_0 @ 0x5581] Single frame detection: TFF:   280 BFF:     0 Progressive:    40 Undetermined:   180\n" +
+				"[Parsed_idet_0 @ 0x5581] Multi frame detection: TFF:   312 BFF:     0 Progressive:    12 Undetermined:   176\n",
+			want:       IdetStats{TFF: 312, BFF: 0, Progressive: 12, Undetermined: 176},
+			interlaced: true,
+			parity:     "tff",
+		},
+		{
+			name:       "bottom field first",
+			log:        "[Parsed_idet_0 @ 0x5581] Multi frame detection: TFF:     3 BFF:   401 Progressive:     0 Undetermined:    96\n",
+			want:       IdetStats{TFF: 3, BFF: 401, Progressive: 0, Undetermined: 96},
+			interlaced: true,
+			parity:     "bff",
+		},
+		{
+			name:       "progressive",
+			log:        "[Parsed_idet_0 @ 0x5581] Multi frame detection: TFF:     0 BFF:     0 Progressive:   498 Undetermined:     2\n",
+			want:       IdetStats{Progressive: 498, Undetermined: 2},
+			interlaced: false,
+			parity:     "tff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIdetStats(tt.log)
+			if err != nil {
+				t.Fatalf("ParseIdetStats() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseIdetStats() = %+v, want %+v", *got, tt.want)
+			}
+			if got.Interlaced() != tt.interlaced {
+				t.Errorf("Interlaced() = %v, want %v", got.Interlaced(), tt.interlaced)
+			}
+			if got.Parity() != tt.parity {
+				t.Errorf("Parity() = %q, want %q", got.Parity(), tt.parity)
+			}
+		})
+	}
+
+	if _, err := ParseIdetStats("frame=  500 fps=250 q=-0.0 Lsize=N/A time=00:00:20.00\n"); err == nil {
+		t.Error("ParseIdetStats() without idet output should fail")
+	}
+}
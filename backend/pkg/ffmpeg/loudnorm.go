@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLoudnormStats loudnorm统计缺少实测值或实测值不是有限数（静音/近乎静音的输入会输出-inf）
+var ErrInvalidLoudnormStats = errors.New("invalid loudnorm stats")
+
+// LoudnormStats loudnorm滤镜以print_format=json输出的响度统计
+// 测量遍的input_*为源音频实测值；标准化遍的output_*为处理后的结果
+type LoudnormStats struct {
+	InputI            float64 // 综合响度（LUFS）
+	InputTP           float64 // 真峰值（dBTP）
+	InputLRA          float64 // 响度范围（LU）
+	InputThresh       float64 // 门限（LUFS）
+	OutputI           float64
+	OutputTP          float64
+	OutputLRA         float64
+	OutputThresh      float64
+	NormalizationType string  // linear或dynamic
+	TargetOffset      float64 // 目标偏移（LU）
+}
+
+// MeasureLoudness 执行loudnorm测量遍，只分析第一条音频流，不产生输出
+func (p *Parser) MeasureLoudness(filePath string, filter string) (*LoudnormStats, error) {
+	cmd := exec.Command(p.binaryPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-af", filter,
+		"-f", "null",
+		"-",
+	)
+
+	// loudnorm的统计信息输出在stderr
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("loudnorm measurement failed: %w, output: %s", err, truncate(string(output), 500))
+	}
+
+	return ParseLoudnormStats(string(output))
+}
+
+// MeasureLoudnessGraph 对filter_complex的音频输出执行loudnorm测量遍，不产生输出
+// inputArgs为输入参数（含-i及其输入选项），filterComplex中需要包含测量用的loudnorm，输出标签为outLabel
+func (p *Parser) MeasureLoudnessGraph(inputArgs []string, filterComplex string, outLabel string) (*LoudnormStats, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	args = append(args, inputArgs...)
+	args = append(args,
+		"-filter_complex", filterComplex,
+		"-map", "["+outLabel+"]",
+		"-f", "null",
+		"-",
+	)
+	cmd := exec.Command(p.binaryPath, args...)
+
+	// loudnorm的统计信息输出在stderr
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("loudnorm measurement failed: %w, output: %s", err, truncate(string(output), 500))
+	}
+
+	return ParseLoudnormStats(string(output))
+}
+
+// ParseLoudnormStats 从ffmpeg日志中解析最后一段loudnorm JSON统计
+// 日志示例: [Parsed_loudnorm_0 @ 0x...] \n{ "input_i" : "-23.54", ... }
+func ParseLoudnormStats(stderrLog string) (*LoudnormStats, error) {
+	marker := strings.LastIndex(stderrLog, "[Parsed_loudnorm")
+	if marker < 0 {
+		return nil, fmt.Errorf("loudnorm stats not found in ffmpeg output")
+	}
+	start := strings.Index(stderrLog[marker:], "{")
+	end := strings.Index(stderrLog[marker:], "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm stats not found in ffmpeg output")
+	}
+
+	// loudnorm输出的数值均为字符串（可能是-inf）
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(stderrLog[marker+start:marker+end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("parse loudnorm stats failed: %w", err)
+	}
+
+	value := func(key string) float64 {
+		v, _ := strconv.ParseFloat(raw[key], 64)
+		return v
+	}
+
+	// 第二遍loudnorm需要的实测值必须存在且为有限数
+	measured := make(map[string]float64)
+	for _, key := range []string{"input_i", "input_tp", "input_lra", "input_thresh", "target_offset"} {
+		v, err := strconv.ParseFloat(raw[key], 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("%w: %s is %q", ErrInvalidLoudnormStats, key, raw[key])
+		}
+		measured[key] = v
+	}
+
+	return &LoudnormStats{
+		InputI:            measured["input_i"],
+		InputTP:           measured["input_tp"],
+		InputLRA:          measured["input_lra"],
+		InputThresh:       measured["input_thresh"],
+		OutputI:           value("output_i"),
+		OutputTP:          value("output_tp"),
+		OutputLRA:         value("output_lra"),
+		OutputThresh:      value("output_thresh"),
+		NormalizationType: raw["normalization_type"],
+		TargetOffset:      measured["target_offset"],
+	}, nil
+}
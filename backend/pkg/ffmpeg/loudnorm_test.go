@@ -0,0 +1,98 @@
+package ffmpeg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLoudnormStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		want    LoudnormStats
+		wantErr bool
+	}{
+		{
+			name: "measurement pass",
+			log: "size=N/A time=00:03:12.00 bitrate=N/A speed= 180x\n" +
+				"[Parsed_loudnorm_0 @ 0x55f4] \n" +
+				"{\n" +
+				"\t\"input_i\" : \"-23.54\",\n" +
+				"\t\"input_tp\" : \"-7.96\",\n" +
+				"\t\"input_lra\" : \"6.20\",\n" +
+				"\t\"input_thresh\" : \"-34.01\",\n" +
+				"\t\"output_i\" : \"-16.02\",\n" +
+				"\t\"output_tp\" : \"-1.50\",\n" +
+				"\t\"output_lra\" : \"5.90\",\n" +
+				"\t\"output_thresh\" : \"-26.44\",\n" +
+				"\t\"normalization_type\" : \"dynamic\",\n" +
+				"\t\"target_offset\" : \"0.02\"\n" +
+				"}\n",
+			want: LoudnormStats{
+				InputI: -23.54, InputTP: -7.96, InputLRA: 6.2, InputThresh: -34.01,
+				OutputI: -16.02, OutputTP: -1.5, OutputLRA: 5.9, OutputThresh: -26.44,
+				NormalizationType: "dynamic", TargetOffset: 0.02,
+			},
+		},
+		{
+			name: "last block wins",
+			log: "[Parsed_loudnorm_0 @ 0x1] \n{ \"input_i\" : \"-30.00\", \"input_tp\" : \"-9.00\", \"input_lra\" : \"4.00\", \"input_thresh\" : \"-40.00\", \"normalization_type\" : \"dynamic\", \"target_offset\" : \"0.10\" }\n" +
+				"[Parsed_loudnorm_1 @ 0x2] \n{ \"input_i\" : \"-18.00\", \"input_tp\" : \"-2.00\", \"input_lra\" : \"3.00\", \"input_thresh\" : \"-28.00\", \"normalization_type\" : \"linear\", \"target_offset\" : \"0.00\" }\n",
+			want: LoudnormStats{InputI: -18, InputTP: -2, InputLRA: 3, InputThresh: -28, NormalizationType: "linear"},
+		},
+		{
+			name:    "missing stats",
+			log:     "Output #0, null, to 'pipe:':\n",
+			wantErr: true,
+		},
+		{
+			name:    "truncated json",
+			log:     "[Parsed_loudnorm_0 @ 0x1] \n{ \"input_i\" : \"-23.54\",\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLoudnormStats(tt.log)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLoudnormStats() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLoudnormStats() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseLoudnormStats() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoudnormStatsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{
+			// 静音输入时loudnorm输出-inf
+			name: "silence",
+			log:  "[Parsed_loudnorm_0 @ 0x1] \n{ \"input_i\" : \"-inf\", \"input_tp\" : \"-inf\", \"input_lra\" : \"0.00\", \"input_thresh\" : \"-inf\", \"normalization_type\" : \"dynamic\", \"target_offset\" : \"inf\" }\n",
+		},
+		{
+			name: "missing key",
+			log:  "[Parsed_loudnorm_0 @ 0x1] \n{ \"input_i\" : \"-23.54\", \"input_tp\" : \"-7.96\", \"input_lra\" : \"6.20\", \"normalization_type\" : \"dynamic\", \"target_offset\" : \"0.02\" }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLoudnormStats(tt.log)
+			if !errors.Is(err, ErrInvalidLoudnormStats) {
+				t.Fatalf("ParseLoudnormStats() = %+v, %v, want ErrInvalidLoudnormStats", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// 运动分析时的缩小尺寸：分析结果归一化为相对坐标，拉伸不影响结果
+const (
+	motionAnalysisWidth  = 320
+	motionAnalysisHeight = 180
+)
+
+// MotionSample 某一时刻画面中运动区域的中心（相对坐标0-1）
+type MotionSample struct {
+	Time    float64
+	CenterX float64
+	CenterY float64
+}
+
+// motionPattern 匹配cropdetect日志中的有效区域和时间戳
+// 日志示例: [Parsed_cropdetect_3 @ 0x...] x1:96 x2:207 y1:40 y2:151 w:112 h:112 x:96 y:40 pts:12 t:6.000000 crop=112:112:96:40
+var motionPattern = regexp.MustCompile(`\[Parsed_cropdetect.*x1:(-?\d+) x2:(-?\d+) y1:(-?\d+) y2:(-?\d+) .* t:([\d.]+)`)
+
+// DetectMotion 执行低成本的运动分析遍：按sampleFPS抽帧并缩小，相邻帧做差后用cropdetect找出变化区域
+// 只有静止画面的采样点（差值全黑）不返回，不产生输出
+func (p *Parser) DetectMotion(filePath string, sampleFPS float64) ([]MotionSample, error) {
+	filter := fmt.Sprintf("fps=%.2f,scale=%d:%d,tblend=all_mode=difference,cropdetect=limit=24:round=2:reset=1",
+		sampleFPS, motionAnalysisWidth, motionAnalysisHeight)
+	cmd := exec.Command(p.binaryPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-vf", filter,
+		"-f", "null",
+		"-",
+	)
+
+	// cropdetect的结果输出在stderr
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("motion analysis failed: %w, output: %s", err, truncate(string(output), 500))
+	}
+
+	return ParseMotionSamples(string(output)), nil
+}
+
+// ParseMotionSamples 从ffmpeg日志中解析各采样点运动区域的中心
+// 没有运动时cropdetect给出x1>x2的空区域，这类采样点被跳过
+func ParseMotionSamples(stderrLog string) []MotionSample {
+	var samples []MotionSample
+	for _, match := range motionPattern.FindAllStringSubmatch(stderrLog, -1) {
+		x1, _ := strconv.Atoi(match[1])
+		x2, _ := strconv.Atoi(match[2])
+		y1, _ := strconv.Atoi(match[3])
+		y2, _ := strconv.Atoi(match[4])
+		t, _ := strconv.ParseFloat(match[5], 64)
+		if x2 <= x1 || y2 <= y1 {
+			continue
+		}
+
+		samples = append(samples, MotionSample{
+			Time:    t,
+			CenterX: float64(x1+x2) / 2 / motionAnalysisWidth,
+			CenterY: float64(y1+y2) / 2 / motionAnalysisHeight,
+		})
+	}
+	return samples
+}
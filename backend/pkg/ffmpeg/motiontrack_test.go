@@ -0,0 +1,26 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMotionSamples(t *testing.T) {
+	log := "[Parsed_cropdetect_3 @ 0x55a0] x1:96 x2:207 y1:40 y2:151 w:112 h:112 x:96 y:40 pts:1 t:0.500000 limit:0.094118 crop=112:112:96:40\n" +
+		// 静止画面：差值全黑，x1>x2
+		"[Parsed_cropdetect_3 @ 0x55a0] x1:319 x2:0 y1:179 y2:0 w:-320 h:-180 x:320 y:180 pts:2 t:1.000000 limit:0.094118 crop=-320:-180:320:180\n" +
+		"frame=    3 fps=0.0 q=-0.0 size=N/A time=00:00:01.50 bitrate=N/A speed=3x\n" +
+		"[Parsed_cropdetect_3 @ 0x55a0] x1:0 x2:160 y1:0 y2:90 w:160 h:90 x:0 y:0 pts:3 t:1.500000 limit:0.094118 crop=160:90:0:0\n"
+
+	want := []MotionSample{
+		{Time: 0.5, CenterX: 151.5 / 320, CenterY: 95.5 / 180},
+		{Time: 1.5, CenterX: 0.25, CenterY: 0.25},
+	}
+	if got := ParseMotionSamples(log); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMotionSamples() = %+v, want %+v", got, want)
+	}
+
+	if got := ParseMotionSamples("Stream mapping:\n"); len(got) != 0 {
+		t.Errorf("ParseMotionSamples() without cropdetect output = %+v, want none", got)
+	}
+}
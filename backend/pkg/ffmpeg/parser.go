@@ -14,8 +14,19 @@ type MediaInfo struct {
 	Height      int     // 高度
 	FPS         float64 // 帧率
 	TotalFrames int     // 总帧数
-	AudioCodec  string  // 音频编码
+	AudioCodec  string  // 音频编码（无音频流时为空）
 	VideoCodec  string  // 视频编码
+	SampleRate  int     // 音频采样率
+	Channels    int     // 音频声道数
+	Rotation    int     // 显示时需顺时针旋转的角度（0/90/180/270），来自rotate标签或display matrix
+}
+
+// DisplaySize 获取按旋转元数据校正后的显示尺寸（ffmpeg解码时默认自动旋转）
+func (m *MediaInfo) DisplaySize() (int, int) {
+	if m.Rotation == 90 || m.Rotation == 270 {
+		return m.Height, m.Width
+	}
+	return m.Width, m.Height
 }
 
 // Parser FFmpeg解析器
@@ -35,7 +46,7 @@ func (p *Parser) GetMediaInfo(filePath string) (*MediaInfo, error) {
 	cmd := exec.Command("ffprobe",
 		"-v", "error",
 		"-select_streams", "v:0",
-		"-show_entries", "stream=duration,width,height,r_frame_rate,codec_name",
+		"-show_entries", "stream=duration,width,height,r_frame_rate,codec_name:stream_tags=rotate:stream_side_data=rotation",
 		"-of", "default=noprint_wrappers=1",
 		filePath,
 	)
@@ -67,9 +78,27 @@ func (p *Parser) GetMediaInfo(filePath string) (*MediaInfo, error) {
 			info.FPS = p.parseFPS(value)
 		case "codec_name":
 			info.VideoCodec = value
+		case "TAG:rotate":
+			// 旧式rotate标签：顺时针角度
+			rotate, _ := strconv.Atoi(value)
+			info.Rotation = normalizeRotation(rotate)
+		case "rotation":
+			// display matrix：逆时针角度（如手机竖拍为-90）
+			rotation, _ := strconv.ParseFloat(value, 64)
+			info.Rotation = normalizeRotation(-int(rotation))
 		}
 	}
 
+	// 部分容器（如mkv/webm）的视频流不带时长，使用容器时长
+	if info.Duration == 0 {
+		if duration, err := p.GetAudioDuration(filePath); err == nil {
+			info.Duration = duration
+		}
+	}
+
+	// 补充音频流信息（无音频流时保持为空）
+	p.fillAudioInfo(filePath, info)
+
 	// 计算总帧数
 	if info.Duration > 0 && info.FPS > 0 {
 		info.TotalFrames = int(info.Duration * info.FPS)
@@ -78,6 +107,43 @@ func (p *Parser) GetMediaInfo(filePath string) (*MediaInfo, error) {
 	return info, nil
 }
 
+// normalizeRotation 将角度归一化到0-359
+func normalizeRotation(degrees int) int {
+	return ((degrees % 360) + 360) % 360
+}
+
+// fillAudioInfo 获取第一条音频流的编码、采样率和声道数
+func (p *Parser) fillAudioInfo(filePath string, info *MediaInfo) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,sample_rate,channels",
+		"-of", "default=noprint_wrappers=1",
+		filePath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "codec_name":
+			info.AudioCodec = parts[1]
+		case "sample_rate":
+			info.SampleRate, _ = strconv.Atoi(parts[1])
+		case "channels":
+			info.Channels, _ = strconv.Atoi(parts[1])
+		}
+	}
+}
+
 // GetAudioDuration 获取音频时长
 func (p *Parser) GetAudioDuration(filePath string) (float64, error) {
 	cmd := exec.Command("ffprobe",
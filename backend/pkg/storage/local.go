@@ -47,6 +47,11 @@ func (s *LocalStorage) UploadFile(localPath string, key string) (string, error)
 	return localPath, nil
 }
 
+// UploadDir 本地存储不需要上传
+func (s *LocalStorage) UploadDir(localDir string, keyPrefix string) error {
+	return nil
+}
+
 // DeleteLocalFile 删除本地文件
 func (s *LocalStorage) DeleteLocalFile(path string) error {
 	if err := os.Remove(path); err != nil {
@@ -55,6 +60,14 @@ func (s *LocalStorage) DeleteLocalFile(path string) error {
 	return nil
 }
 
+// DeleteLocalDir 删除本地目录
+func (s *LocalStorage) DeleteLocalDir(path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("delete local dir failed: %w", err)
+	}
+	return nil
+}
+
 func (s *LocalStorage) GetUploadPath(filename string) string {
 	return filepath.Join(s.uploadDir, filename)
 }
@@ -114,6 +114,28 @@ func (s *QiniuStorage) UploadFile(localPath string, key string) (string, error)
 	return url, nil
 }
 
+// UploadDir 上传目录下的所有文件到七牛云，保持相对目录结构
+func (s *QiniuStorage) UploadDir(localDir string, keyPrefix string) error {
+	return filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, path)
+		if err != nil {
+			return fmt.Errorf("resolve relative path failed: %w", err)
+		}
+		key := fmt.Sprintf("%s/%s", keyPrefix, filepath.ToSlash(rel))
+		if _, err := s.UploadFile(path, key); err != nil {
+			return fmt.Errorf("upload %s failed: %w", rel, err)
+		}
+		return nil
+	})
+}
+
 // DeleteLocalFile 删除本地文件
 func (s *QiniuStorage) DeleteLocalFile(path string) error {
 	if err := os.Remove(path); err != nil {
@@ -122,6 +144,14 @@ func (s *QiniuStorage) DeleteLocalFile(path string) error {
 	return nil
 }
 
+// DeleteLocalDir 删除本地目录
+func (s *QiniuStorage) DeleteLocalDir(path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("delete local dir failed: %w", err)
+	}
+	return nil
+}
+
 // GetUploadPath 获取上传文件的本地路径
 func (s *QiniuStorage) GetUploadPath(filename string) string {
 	return filepath.Join(s.uploadDir, filename)
@@ -10,9 +10,15 @@ type Storage interface {
 	// UploadFile 上传文件到云存储，返回访问URL和错误
 	UploadFile(localPath string, key string) (string, error)
 
+	// UploadDir 上传整个目录到云存储，文件key为 keyPrefix/相对路径
+	UploadDir(localDir string, keyPrefix string) error
+
 	// DeleteLocalFile 删除本地文件
 	DeleteLocalFile(path string) error
 
+	// DeleteLocalDir 删除本地目录及其中所有文件
+	DeleteLocalDir(path string) error
+
 	// GetUploadPath 获取上传文件的本地路径
 	GetUploadPath(filename string) string
 
@@ -7,6 +7,7 @@ import (
 	"github.com/fangzio/ffmpeg-platform/model"
 	"github.com/fangzio/ffmpeg-platform/pkg/downloader"
 	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+	"os"
 	"path/filepath"
 )
 
@@ -83,6 +84,81 @@ func (s *FFmpegService) BuildImageAudioToVideoCommand(params model.TaskInputPara
 		"-loop", "1", // 循环图片
 		"-i", localImagePath, // 使用本地图片路径
 		"-i", localAudioPath, // 使用本地音频路径
+	}
+
+	// 视频缩放（按适配模式保持或忽略图片宽高比）
+	graph, videoLabel := "", "0:v"
+	if params.Width > 0 && params.Height > 0 {
+		graph = s.buildFitFilter(params.Fit, "0:v", "scaled", params.Width, params.Height)
+		videoLabel = "scaled"
+	}
+
+	// 音频可视化（audiogram）：按画面尺寸生成波形/频谱动画叠加到图片上
+	if params.Visualizer != nil {
+		frameWidth, frameHeight := params.Width, params.Height
+		if frameWidth == 0 || frameHeight == 0 {
+			// 未缩放时以图片原始尺寸为准
+			imageInfo, err := s.parser.GetMediaInfo(localImagePath)
+			if err != nil {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, fmt.Errorf("get image info failed: %w", err)
+			}
+			frameWidth, frameHeight = imageInfo.Width, imageInfo.Height
+		}
+
+		visualizer := s.buildVisualizerFilter(params.Visualizer, "1:a", videoLabel, "audiogram", frameWidth, frameHeight, fps)
+		if graph != "" {
+			graph += ";"
+		}
+		graph += visualizer
+		videoLabel = "audiogram"
+	}
+
+	// 通用视频效果（水印等），额外输入从索引2开始
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, "", graph, videoLabel, 2, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	videoMap := videoLabel
+	if graph != "" {
+		videoMap = "[" + videoLabel + "]"
+	}
+
+	// 响度标准化（两遍loudnorm的第二遍），加入filter_complex以便完整记录
+	audioMap := "1:a"
+	if params.Loudness != nil {
+		loudnorm, err := s.buildLoudnormFilter(params.Loudness, localAudioPath, params.SampleRate)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if graph != "" {
+			graph += ";"
+		}
+		graph += fmt.Sprintf("[1:a]%s[aout]", loudnorm)
+		audioMap = "[aout]"
+	}
+
+	if graph != "" {
+		args = append(args, "-filter_complex", graph)
+	}
+	args = append(args,
+		"-map", videoMap,
+		"-map", audioMap,
+	)
+
+	args = append(args,
 		"-c:v", s.getVideoCodec(params.VideoCodec), // 视频编码器
 		"-preset", "ultrafast", // 使用最快的编码preset，提升处理速度
 		"-c:a", s.getAudioCodec(params.AudioCodec), // 音频编码器
@@ -90,12 +166,7 @@ func (s *FFmpegService) BuildImageAudioToVideoCommand(params model.TaskInputPara
 		"-b:a", s.getAudioBitrate(params.AudioBitrate), // 音频码率
 		"-r", fmt.Sprintf("%d", fps), // 帧率
 		"-pix_fmt", "yuv420p", // 像素格式（兼容性）
-	}
-
-	// 视频缩放
-	if params.Width > 0 && params.Height > 0 {
-		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", params.Width, params.Height))
-	}
+	)
 
 	// 音频循环
 	if params.AudioLoop {
@@ -104,6 +175,8 @@ func (s *FFmpegService) BuildImageAudioToVideoCommand(params model.TaskInputPara
 		args = append(args, "-shortest") // 以最短流为准
 	}
 
+	args = append(args, subtitleArgs...)
+
 	// 输出格式
 	args = append(args,
 		"-f", s.getOutputFormat(params.OutputFormat),
@@ -124,6 +197,16 @@ func (s *FFmpegService) ExecuteWithProgress(
 	return s.executor.Execute(ctx, args, totalFrames, callback)
 }
 
+// ExecuteWithDuration 执行ffmpeg命令并按已处理时长报告进度（纯音频输出）
+func (s *FFmpegService) ExecuteWithDuration(
+	ctx context.Context,
+	args []string,
+	totalDuration float64,
+	callback ffmpeg.ProgressCallback,
+) *ffmpeg.ExecuteResult {
+	return s.executor.ExecuteWithDuration(ctx, args, totalDuration, callback)
+}
+
 // 辅助方法：提供默认值和参数验证
 
 func (s *FFmpegService) getVideoCodec(codec string) string {
@@ -155,8 +238,11 @@ func (s *FFmpegService) getAudioBitrate(bitrate string) string {
 }
 
 func (s *FFmpegService) getOutputFormat(format string) string {
-	if format == "" {
+	switch format {
+	case "":
 		return "mp4" // 默认MP4
+	case "mkv":
+		return "matroska" // mkv的muxer名称为matroska
 	}
 	return format
 }
@@ -165,7 +251,12 @@ func (s *FFmpegService) getOutputFormat(format string) string {
 func (s *FFmpegService) ValidateInputs(params model.TaskInputParams) error {
 	// 验证图片文件
 	// 优先检查多图片场景（ImagePaths），如果不存在则检查单图片场景（ImagePath）
-	if len(params.ImagePaths) > 0 {
+	if len(params.Slides) > 0 {
+		// 幻灯片列表场景
+		if err := s.validateSlides(params.Slides); err != nil {
+			return err
+		}
+	} else if len(params.ImagePaths) > 0 {
 		// 多图片轮播场景
 		for i, imagePath := range params.ImagePaths {
 			if imagePath == "" {
@@ -182,6 +273,187 @@ func (s *FFmpegService) ValidateInputs(params model.TaskInputParams) error {
 		}
 	}
 
+	// 验证转场效果
+	if err := s.validateTransition(params.TransitionType); err != nil {
+		return err
+	}
+	for i, transition := range params.Transitions {
+		if err := s.validateTransition(transition); err != nil {
+			return fmt.Errorf("transitions[%d]: %w", i, err)
+		}
+	}
+
+	// 验证音频可视化参数
+	if params.Visualizer != nil {
+		if err := s.validateVisualizer(params.Visualizer); err != nil {
+			return err
+		}
+	}
+
+	// 验证画质修复参数
+	if params.Restore != nil {
+		if err := s.validateRestore(params.Restore); err != nil {
+			return err
+		}
+	}
+
+	// 验证调色参数
+	if params.Color != nil {
+		if err := s.validateColor(params.Color); err != nil {
+			return err
+		}
+	}
+
+	// 验证输出预设
+	if params.Preset != nil {
+		if err := s.validatePreset(params); err != nil {
+			return err
+		}
+	}
+
+	// 验证画面适配参数
+	if params.Fit != nil {
+		if err := s.validateFit(params.Fit); err != nil {
+			return err
+		}
+	}
+
+	// 验证字幕样式
+	if params.CaptionStyle != nil {
+		if err := s.validateTextStyle(params.CaptionStyle); err != nil {
+			return err
+		}
+	}
+
+	// 验证文字叠加和标题卡
+	if err := s.validateTextOverlays(params.TextOverlays, params.Variables); err != nil {
+		return err
+	}
+	if err := s.validateTitleCards(params.TitleCards, params.Variables); err != nil {
+		return err
+	}
+	if len(params.TitleCards) > 0 && params.Subtitle != nil && subtitleMode(params.Subtitle) == "soft" {
+		return fmt.Errorf("title_cards cannot be combined with soft subtitles (the subtitle track would be out of sync), use burn mode instead")
+	}
+	for i, slide := range params.Slides {
+		if err := validateTemplate(slide.Caption, params.Variables); err != nil {
+			return fmt.Errorf("slides[%d]: %w", i, err)
+		}
+	}
+
+	// 验证运动效果
+	if err := s.validateMotion(params.Motion); err != nil {
+		return err
+	}
+	for i, motion := range params.ImageMotions {
+		if err := s.validateMotion(motion); err != nil {
+			return fmt.Errorf("image_motions[%d]: %w", i, err)
+		}
+	}
+	if params.MotionZoom != 0 && (params.MotionZoom <= 1 || params.MotionZoom > 3) {
+		return fmt.Errorf("motion_zoom must be between 1 and 3")
+	}
+
+	// 验证水印
+	if params.Watermark != nil {
+		if err := s.validateWatermark(params.Watermark); err != nil {
+			return err
+		}
+	}
+
+	// 验证字幕
+	if params.Subtitle != nil {
+		if err := s.validateSubtitle(params.Subtitle, params.OutputFormat); err != nil {
+			return err
+		}
+	}
+
+	// 验证音频输出参数
+	if params.SampleRate < 0 || params.Channels < 0 {
+		return fmt.Errorf("sample_rate and channels must not be negative")
+	}
+
+	// 验证响度标准化参数
+	if params.Loudness != nil {
+		if err := s.validateLoudness(params.Loudness); err != nil {
+			return err
+		}
+	}
+
+	// 验证动图参数
+	if params.GIF != nil {
+		if err := s.validateGIF(params.GIF); err != nil {
+			return err
+		}
+	}
+
+	// 验证缩略图参数
+	if params.Thumbnails != nil {
+		if err := s.validateThumbnails(params.Thumbnails); err != nil {
+			return err
+		}
+	}
+
+	// 验证HLS/DASH参数
+	if err := s.validateHLS(params.HLS); err != nil {
+		return err
+	}
+	if err := s.validateDASH(params.DASH); err != nil {
+		return err
+	}
+
+	// 验证输入视频
+	if params.InputPath != "" {
+		if err := s.parser.ValidateFile(params.InputPath); err != nil {
+			return fmt.Errorf("invalid input video: %w", err)
+		}
+	}
+
+	// 验证待拼接视频
+	for i, inputPath := range params.InputPaths {
+		if err := s.parser.ValidateFile(inputPath); err != nil {
+			return fmt.Errorf("invalid input video at index %d: %w", i, err)
+		}
+	}
+
+	// 验证变速参数
+	if params.Retime != nil {
+		if err := s.validateRetime(params.Retime); err != nil {
+			return err
+		}
+	}
+
+	// 验证裁剪/旋转参数
+	if params.Reframe != nil {
+		if err := s.validateReframe(params.Reframe); err != nil {
+			return err
+		}
+	}
+
+	// 验证绿幕抠像参数
+	if params.ChromaKey != nil {
+		if err := s.validateChromaKey(params.ChromaKey); err != nil {
+			return err
+		}
+	}
+
+	// 验证画面合成参数
+	if params.Compose != nil {
+		if err := s.validateCompose(params.Compose); err != nil {
+			return err
+		}
+	}
+
+	// 验证裁剪时间段
+	for i, r := range s.clipRanges(params) {
+		if r.Start < 0 || r.Duration < 0 || r.End < 0 {
+			return fmt.Errorf("invalid clip range at index %d: negative time", i)
+		}
+		if r.Duration == 0 && r.End > 0 && r.End <= r.Start {
+			return fmt.Errorf("invalid clip range at index %d: end %.2f must be after start %.2f", i, r.End, r.Start)
+		}
+	}
+
 	// 验证音频文件
 	// 优先检查单图片+音频场景的 AudioPath，其次检查多图片轮播的 BackgroundAudio
 	if params.AudioPath != "" {
@@ -193,6 +465,76 @@ func (s *FFmpegService) ValidateInputs(params model.TaskInputParams) error {
 			return fmt.Errorf("invalid background audio file: %w", err)
 		}
 	}
+	if params.Voiceover != "" {
+		if err := s.parser.ValidateFile(params.Voiceover); err != nil {
+			return fmt.Errorf("invalid voiceover file: %w", err)
+		}
+	}
+	if params.AudioFadeIn < 0 || params.AudioFadeOut < 0 || params.BackgroundVolume < 0 {
+		return fmt.Errorf("audio_fade_in, audio_fade_out and background_volume must not be negative")
+	}
+	if params.DuckingRatio != 0 && (params.DuckingRatio < 1 || params.DuckingRatio > 20) {
+		return fmt.Errorf("ducking_ratio must be between 1 and 20")
+	}
+
+	return nil
+}
+
+// ValidateTaskOptions 验证参数是否适用于任务类型（不适用的参数会被构建命令时忽略，需要提前拒绝）
+func (s *FFmpegService) ValidateTaskOptions(taskType string, params model.TaskInputParams) error {
+	// 目录形式的输出：HLS/DASH只有转码（多码率阶梯）支持，缩略图产物只能由thumbnails任务生成
+	switch params.OutputFormat {
+	case "hls", "dash":
+		if taskType != "transcode" && taskType != "watermark" {
+			return fmt.Errorf("output_format %s is only supported for transcode and watermark tasks", params.OutputFormat)
+		}
+		// 每个码率档位都要缩放后重新编码
+		if params.VideoCodec == "copy" {
+			return fmt.Errorf("output_format %s requires re-encoding, video_codec cannot be copy", params.OutputFormat)
+		}
+	case "thumbnails":
+		if taskType != "thumbnails" {
+			return fmt.Errorf("output_format thumbnails is not supported for %s tasks, use the thumbnails task type", taskType)
+		}
+	}
+
+	// 音频转换只支持纯音频容器
+	if taskType == "audio_convert" {
+		if _, ok := audioFormats[s.getAudioOutputFormat(params.OutputFormat)]; !ok {
+			return fmt.Errorf("unsupported audio output format: %s", params.OutputFormat)
+		}
+	}
+
+	// 输出预设
+	if params.Preset != nil {
+		switch taskType {
+		case "gif", "audio_convert", "thumbnails":
+			return fmt.Errorf("preset is not supported for %s tasks", taskType)
+		}
+		if presetReframe(params.Preset) == "track" && !presetTrackTasks[taskType] {
+			return fmt.Errorf("preset reframe track is only supported for transcode and watermark tasks")
+		}
+	}
+
+	// 响度标准化
+	if params.Loudness != nil {
+		if !loudnessTasks[taskType] {
+			return fmt.Errorf("loudness is not supported for %s tasks", taskType)
+		}
+		if params.OutputFormat == "hls" || params.OutputFormat == "dash" {
+			return fmt.Errorf("loudness normalization is not supported for %s output", params.OutputFormat)
+		}
+	}
+
+	// 缩略图附加步骤基于输出视频生成，纯音频输出没有画面
+	if params.Thumbnails != nil && taskType == "audio_convert" {
+		return fmt.Errorf("thumbnails is not supported for audio_convert tasks")
+	}
+
+	// 画质修复
+	if params.Restore != nil && !restoreTasks[taskType] {
+		return fmt.Errorf("restore is not supported for %s tasks", taskType)
+	}
 
 	return nil
 }
@@ -204,13 +546,39 @@ func (s *FFmpegService) GenerateOutputPath(taskID string, format string) string
 }
 
 // GenerateOutputName 生成输出文件名称
+// 目录形式的输出（HLS/DASH/缩略图）返回"任务ID/主文件名"
 func (s *FFmpegService) GenerateOutputName(taskID string, format string) string {
-	if format == "" {
+	switch format {
+	case "":
 		format = "mp4"
+	case "hls":
+		return fmt.Sprintf("%s/%s", taskID, HLSMasterPlaylist)
+	case "dash":
+		return fmt.Sprintf("%s/%s", taskID, DASHManifest)
+	case "thumbnails":
+		return fmt.Sprintf("%s/%s", taskID, ThumbnailStoryboardFile)
 	}
 	return fmt.Sprintf("%s.%s", taskID, format)
 }
 
+// IsPackagedOutput 判断输出格式是否为目录形式（清单/主播放列表+切片，或缩略图产物）
+func (s *FFmpegService) IsPackagedOutput(format string) bool {
+	return format == "hls" || format == "dash" || format == "thumbnails"
+}
+
+// TaskOutputFormat 获取任务的输出格式，输出固定为图片等产物的任务类型不使用output_format
+func (s *FFmpegService) TaskOutputFormat(task *model.Task) string {
+	switch task.Type {
+	case "thumbnails":
+		return "thumbnails"
+	case "gif":
+		return s.getAnimatedFormat(task.InputParams.OutputFormat)
+	case "audio_convert":
+		return s.getAudioOutputFormat(task.InputParams.OutputFormat)
+	}
+	return task.InputParams.OutputFormat
+}
+
 // CleanupTempFiles 清理临时文件
 func (s *FFmpegService) CleanupTempFiles(tempFiles []string) {
 	for _, f := range tempFiles {
@@ -221,18 +589,51 @@ func (s *FFmpegService) CleanupTempFiles(tempFiles []string) {
 	}
 }
 
+// downloadInput 下载输入文件到本地，下载产生的临时文件会追加到tempFiles以便后续清理
+func (s *FFmpegService) downloadInput(urlOrPath string, tempFiles *[]string) (string, error) {
+	localPath, err := s.downloader.DownloadFile(urlOrPath)
+	if err != nil {
+		return "", err
+	}
+	if localPath != urlOrPath {
+		*tempFiles = append(*tempFiles, localPath)
+	}
+	return localPath, nil
+}
+
+// writeTempFile 在临时目录写入辅助文件（如concat列表），返回文件路径
+func (s *FFmpegService) writeTempFile(pattern string, content string) (string, error) {
+	if err := os.MkdirAll(s.config.Storage.TempDir, 0755); err != nil {
+		return "", fmt.Errorf("create temp dir failed: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.config.Storage.TempDir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file failed: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write temp file failed: %w", err)
+	}
+	return f.Name(), nil
+}
+
 // BuildImageSlideshowCommand 构建多图片轮播视频的ffmpeg命令
 // 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
 func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
 	var tempFiles []string
 
-	if len(params.ImagePaths) == 0 {
+	slides := s.resolveSlides(params)
+	if len(slides) == 0 {
 		return nil, 0, nil, fmt.Errorf("no images provided")
 	}
 
 	// 下载所有图片到本地
-	localImagePaths := make([]string, 0, len(params.ImagePaths))
-	for _, imagePath := range params.ImagePaths {
+	localImagePaths := make([]string, 0, len(slides))
+	for _, slide := range slides {
+		imagePath := slide.imagePath
 		localPath, err := s.downloader.DownloadFile(imagePath)
 		if err != nil {
 			// 清理已下载的文件
@@ -275,48 +676,50 @@ func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams,
 		}
 	}
 
+	// 下载旁白（如果有）
+	var localVoiceoverPath string
+	if params.Voiceover != "" {
+		var err error
+		localVoiceoverPath, err = s.downloadInput(params.Voiceover, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("download voiceover failed: %w", err)
+		}
+	}
+	hasAudio := localAudioPath != "" || localVoiceoverPath != ""
+
 	// 设置默认值
 	fps := params.FPS
 	if fps == 0 {
 		fps = 25
 	}
 
-	imageDuration := params.ImageDuration
-	if imageDuration == 0 {
-		imageDuration = 3.0 // 默认每张图片3秒
-	}
-
 	transitionDur := params.TransitionDur
 	if transitionDur == 0 {
 		transitionDur = 0.5 // 默认转场0.5秒
 	}
 
-	transitionType := params.TransitionType
-	if transitionType == "" {
-		transitionType = "fade" // 默认淡入淡出
+	if err := s.validateSlideTransitions(slides, transitionDur); err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
 	}
 
-	// 计算视频总时长和总帧数
-	numImages := len(localImagePaths)
-	var totalDuration float64
+	// 计算视频总时长和总帧数（按每张图片各自的时长）
+	totalDuration := s.slideshowDuration(slides, transitionDur)
 
-	if transitionType == "none" {
-		// 无转场，直接拼接
-		totalDuration = float64(numImages) * imageDuration
-	} else {
-		// 有转场，图片之间有重叠
-		totalDuration = float64(numImages)*imageDuration - float64(numImages-1)*transitionDur
-	}
-
-	// 如果有音频，视频时长以音频为准
-	if audioDuration > 0 {
+	// 背景音乐不循环且短于幻灯片时，输出以音乐为准（-shortest）
+	if audioDuration > 0 && !params.BackgroundAudioLoop && audioDuration < totalDuration {
 		totalDuration = audioDuration
 	}
 
 	totalFrames := int(totalDuration * float64(fps))
 
 	// 构建filter_complex
-	filterComplex := s.buildSlideshowFilter(localImagePaths, imageDuration, transitionDur, transitionType, params.Width, params.Height, fps)
+	filterComplex, err := s.buildSlideshowFilter(slides, transitionDur, params.Width, params.Height, fps, params.MotionZoom, params.Fit, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
 
 	// 构建ffmpeg命令
 	args := []string{
@@ -324,25 +727,64 @@ func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams,
 		"-stats",
 	}
 
-	// 添加所有图片作为输入
-	for _, imgPath := range localImagePaths {
-		args = append(args, "-loop", "1", "-t", fmt.Sprintf("%.2f", imageDuration), "-i", imgPath)
+	// 添加所有图片作为输入（按输出帧率循环，运动效果按帧计算）
+	for i, imgPath := range localImagePaths {
+		args = append(args, "-loop", "1", "-framerate", fmt.Sprintf("%d", fps), "-t", fmt.Sprintf("%.2f", slides[i].duration), "-i", imgPath)
 	}
 
-	// 添加音频输入（如果有）
+	// 添加音频输入（如果有）：背景音乐需要循环时无限循环输入，由音轨滤镜裁剪到视频时长
+	musicInput, voiceoverInput := -1, -1
 	if localAudioPath != "" {
+		musicInput = countInputs(args)
+		if params.BackgroundAudioLoop {
+			args = append(args, "-stream_loop", "-1")
+		}
 		args = append(args, "-i", localAudioPath)
 	}
+	if localVoiceoverPath != "" {
+		voiceoverInput = countInputs(args)
+		args = append(args, "-i", localVoiceoverPath)
+	}
+
+	// 通用视频效果（水印等），额外输入排在图片和音频之后
+	effectInputs, filterComplex, videoLabel, err := s.appendVideoEffects(params, "", filterComplex, "v", countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 音轨滤镜（音乐淡入淡出、循环裁剪、旁白闪避、响度标准化）同样写入filter_complex
+	if hasAudio {
+		// 响度标准化测量混音后的最终音轨
+		var loudnorm string
+		if params.Loudness != nil {
+			loudnorm, err = s.buildSlideshowLoudnorm(params, localAudioPath, localVoiceoverPath, totalDuration)
+			if err != nil {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, err
+			}
+		}
+		filterComplex += ";" + s.buildSlideshowAudio(params, musicInput, voiceoverInput, totalDuration, loudnorm)
+	}
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
 
 	// 添加filter_complex
 	args = append(args,
 		"-filter_complex", filterComplex,
-		"-map", "[v]", // 映射视频流
+		"-map", "["+videoLabel+"]", // 映射视频流
 	)
 
 	// 映射音频流
-	if localAudioPath != "" {
-		args = append(args, "-map", fmt.Sprintf("%d:a", len(localImagePaths)))
+	if hasAudio {
+		args = append(args, "-map", "["+slideshowAudioLabel+"]")
 	}
 
 	// 编码参数
@@ -355,7 +797,7 @@ func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams,
 	)
 
 	// 音频编码参数
-	if localAudioPath != "" {
+	if hasAudio {
 		args = append(args,
 			"-c:a", s.getAudioCodec(params.AudioCodec),
 			"-b:a", s.getAudioBitrate(params.AudioBitrate),
@@ -363,6 +805,8 @@ func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams,
 		)
 	}
 
+	args = append(args, subtitleArgs...)
+
 	// 输出参数
 	args = append(args,
 		"-f", s.getOutputFormat(params.OutputFormat),
@@ -374,8 +818,9 @@ func (s *FFmpegService) BuildImageSlideshowCommand(params model.TaskInputParams,
 }
 
 // buildSlideshowFilter 构建幻灯片的filter_complex
-func (s *FFmpegService) buildSlideshowFilter(imagePaths []string, duration, transitionDur float64, transitionType string, width, height, fps int) string {
-	numImages := len(imagePaths)
+// 每张图片按各自的时长、运动效果和字幕预处理，再按各衔接处的转场链式合并，none表示直接拼接
+func (s *FFmpegService) buildSlideshowFilter(slides []slideshowSlide, transitionDur float64, width, height, fps int, zoom float64, fit *model.FitOptions, tempFiles *[]string) (string, error) {
+	numImages := len(slides)
 
 	// 设置默认尺寸
 	if width == 0 {
@@ -387,56 +832,253 @@ func (s *FFmpegService) buildSlideshowFilter(imagePaths []string, duration, tran
 
 	var filter string
 
-	if transitionType == "none" {
-		// 无转场效果 - 简单拼接
-		for i := 0; i < numImages; i++ {
-			filter += fmt.Sprintf("[%d:v]scale=%d:%d,setsar=1,fps=%d,settb=AVTB[v%d];", i, width, height, fps, i)
-		}
-		// 拼接所有视频片段
-		for i := 0; i < numImages; i++ {
-			filter += fmt.Sprintf("[v%d]", i)
-		}
-		filter += fmt.Sprintf("concat=n=%d:v=1:a=0[v]", numImages)
-	} else if transitionType == "fade" {
-		// 淡入淡出转场
-		// 首先缩放所有图片
-		for i := 0; i < numImages; i++ {
-			filter += fmt.Sprintf("[%d:v]scale=%d:%d,setsar=1,fps=%d,settb=AVTB[v%d];", i, width, height, fps, i)
-		}
-
-		// 构建淡入淡出链 - 正确的xfade链式语法
-		// xfade需要两个输入，产生一个输出，然后这个输出再和下一个输入做xfade
-		if numImages == 2 {
-			// 只有2张图片的简单情况
-			offset := duration - transitionDur
-			filter += fmt.Sprintf("[v0][v1]xfade=transition=fade:duration=%.2f:offset=%.2f[v]", transitionDur, offset)
+	// 首先按适配模式缩放所有图片（以及运动效果、字幕）
+	for i, slide := range slides {
+		var caption string
+		if slide.caption != "" {
+			var err error
+			caption, err = s.buildDrawText(slide.caption, slide.style, height, tempFiles)
+			if err != nil {
+				return "", err
+			}
+		}
+		filter += s.buildSlideInput(i, width, height, fps, slide.duration, slide.motion, zoom, fit, caption)
+	}
+
+	if numImages == 1 {
+		return filter + "[v0]null[v]", nil
+	}
+
+	// 逐个衔接处链式合并：[vt(i-1)] + [vi] -> [vti]，最后一个输出为[v]
+	// xfade的offset为已合并流的长度减去转场时长；直接拼接时使用concat
+	prev := "v0"
+	length := slides[0].duration
+	for i := 1; i < numImages; i++ {
+		out := fmt.Sprintf("vt%d", i)
+		if i == numImages-1 {
+			out = "v"
+		}
+
+		transition := slides[i-1].transition
+		if transition == "none" {
+			filter += fmt.Sprintf("[%s][v%d]concat=n=2:v=1:a=0[%s]", prev, i, out)
+			length += slides[i].duration
 		} else {
-			// 多张图片的链式xfade
-			// 第一个xfade: [v0] + [v1] -> [vt1]
-			offset := duration - transitionDur
-			filter += fmt.Sprintf("[v0][v1]xfade=transition=fade:duration=%.2f:offset=%.2f[vt1];", transitionDur, offset)
-
-			// 后续的xfade: [vt(i-1)] + [vi] -> [vti] 或 [v]
-			for i := 2; i < numImages; i++ {
-				// 计算累积的offset
-				// 每次xfade的输入流长度 = 前面所有图片的总时长 - 前面所有转场的重叠时长
-				// 对于第i个图片(从0开始): 累积长度 = i * duration - (i-1) * transitionDur
-				cumulativeLength := float64(i)*duration - float64(i-1)*transitionDur
-				offset := cumulativeLength - transitionDur
-
-				if i == numImages-1 {
-					// 最后一个xfade，输出为[v]
-					filter += fmt.Sprintf("[vt%d][v%d]xfade=transition=fade:duration=%.2f:offset=%.2f[v]", i-1, i, transitionDur, offset)
-				} else {
-					// 中间的xfade，输出为[vt(i)]
-					filter += fmt.Sprintf("[vt%d][v%d]xfade=transition=fade:duration=%.2f:offset=%.2f[vt%d];", i-1, i, transitionDur, offset, i)
-				}
+			offset := length - transitionDur
+			filter += fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%.2f:offset=%.2f[%s]", prev, i, transition, transitionDur, offset, out)
+			length += slides[i].duration - transitionDur
+		}
+		if out != "v" {
+			filter += ";"
+		}
+		prev = out
+	}
+
+	return filter, nil
+}
+
+// BuildTranscodeCommand 构建视频转码的ffmpeg命令
+// 复用通用的编码、分辨率、帧率、码率参数，未设置的分辨率和帧率保持源视频不变
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildTranscodeCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	// 下载远程视频到本地
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	// 获取视频信息用于计算总帧数
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+
+	// 输出帧率：优先使用参数，否则沿用源视频帧率
+	fps := float64(params.FPS)
+	if fps == 0 {
+		fps = info.FPS
+	}
+	if fps == 0 {
+		fps = 25
+	}
+	totalFrames := int(info.Duration * fps)
+
+	// HLS/DASH输出：一次运行生成多码率阶梯
+	if params.OutputFormat == "hls" || params.OutputFormat == "dash" {
+		if params.Loudness != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("loudness normalization is not supported for %s output", params.OutputFormat)
+		}
+		var args []string
+		if params.OutputFormat == "dash" {
+			args, err = s.buildDASHArgs(params, localInputPath, info, outputPath, &tempFiles)
+		} else {
+			args, err = s.buildHLSArgs(params, localInputPath, info, outputPath, &tempFiles)
+		}
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		return args, totalFrames, tempFiles, nil
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", localInputPath,
+	}
+
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" && s.hasVideoEffects(params) {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("video effects require re-encoding, video_codec cannot be copy")
+	}
+
+	var graph string
+	videoMap := "0:v:0"
+	if videoCodec != "copy" {
+		// 画质修复在缩放之前应用于源视频
+		videoLabel := "0:v:0"
+		if params.Restore != nil {
+			restore, err := s.buildRestoreChain(params.Restore, localInputPath)
+			if err != nil {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, err
+			}
+			if restore != "" {
+				graph = fmt.Sprintf("[0:v:0]%s[restored]", restore)
+				videoLabel = "restored"
 			}
 		}
-	} else {
-		// 默认使用fade
-		return s.buildSlideshowFilter(imagePaths, duration, transitionDur, "fade", width, height, fps)
+
+		// 视频缩放：只指定宽或高时按比例计算另一边（-2保证偶数）
+		if scale := s.buildScaleFilter(params.Width, params.Height); scale != "" {
+			if graph != "" {
+				graph += ";"
+			}
+			graph += fmt.Sprintf("[%s]%s[scaled]", videoLabel, scale)
+			videoLabel = "scaled"
+		}
+
+		// 通用视频效果（水印等），额外输入从索引1开始
+		var effectInputs []string
+		effectInputs, graph, videoLabel, err = s.appendVideoEffects(params, localInputPath, graph, videoLabel, 1, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		args = append(args, effectInputs...)
+		if graph != "" {
+			videoMap = "[" + videoLabel + "]"
+		}
+	}
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	// 响度标准化（两遍loudnorm的第二遍），源视频没有音频时忽略
+	// 有filter_complex时加入同一个graph，保证记录的FilterGraph包含实际执行的音频处理
+	audioCodec := s.getAudioCodec(params.AudioCodec)
+	audioMap := "0:a?" // 源视频可能没有音频
+	var audioFilter string
+	if params.Loudness != nil && info.AudioCodec != "" {
+		if audioCodec == "copy" {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("loudness normalization requires re-encoding, audio_codec cannot be copy")
+		}
+		sampleRate := params.SampleRate
+		if sampleRate == 0 {
+			sampleRate = info.SampleRate
+		}
+		loudnorm, err := s.buildLoudnormFilter(params.Loudness, localInputPath, sampleRate)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if graph != "" {
+			graph += fmt.Sprintf(";[0:a:0]%s[aout]", loudnorm)
+			audioMap = "[aout]"
+		} else {
+			audioFilter = loudnorm
+		}
+	}
+
+	if graph != "" {
+		args = append(args, "-filter_complex", graph)
 	}
+	args = append(args,
+		"-map", videoMap,
+		"-map", audioMap,
+	)
+
+	args = append(args, "-c:v", videoCodec)
+	if videoCodec != "copy" {
+		args = append(args,
+			"-preset", "ultrafast",
+			"-b:v", s.getVideoBitrate(params.VideoBitrate),
+			"-pix_fmt", "yuv420p",
+		)
 
-	return filter
+		if params.FPS > 0 {
+			args = append(args, "-r", fmt.Sprintf("%d", params.FPS))
+		}
+	}
+
+	args = append(args, "-c:a", audioCodec)
+	if audioCodec != "copy" {
+		args = append(args, "-b:a", s.getAudioBitrate(params.AudioBitrate))
+	}
+	if audioFilter != "" {
+		args = append(args, "-af", audioFilter)
+	}
+
+	args = append(args, subtitleArgs...)
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart") // moov前置，便于边下边播
+	}
+
+	args = append(args,
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// buildScaleFilter 构建缩放滤镜，宽高都未指定时返回空字符串
+func (s *FFmpegService) buildScaleFilter(width, height int) string {
+	switch {
+	case width > 0 && height > 0:
+		return fmt.Sprintf("scale=%d:%d", width, height)
+	case width > 0:
+		return fmt.Sprintf("scale=%d:-2", width)
+	case height > 0:
+		return fmt.Sprintf("scale=-2:%d", height)
+	default:
+		return ""
+	}
+}
+
+// BuildWatermarkCommand 构建视频加水印的ffmpeg命令
+// 与转码共用参数，水印作为通用视频效果应用
+func (s *FFmpegService) BuildWatermarkCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	if params.Watermark == nil {
+		return nil, 0, nil, fmt.Errorf("no watermark provided")
+	}
+	return s.BuildTranscodeCommand(params, outputPath)
 }
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// defaultRenditions 默认码率阶梯
+var defaultRenditions = []model.Rendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: "5M", AudioBitrate: "192k"},
+	{Name: "720p", Height: 720, VideoBitrate: "3M", AudioBitrate: "128k"},
+	{Name: "480p", Height: 480, VideoBitrate: "1500k", AudioBitrate: "128k"},
+	{Name: "360p", Height: 360, VideoBitrate: "800k", AudioBitrate: "96k"},
+}
+
+// buildLadderFilter 构建码率阶梯的filter_complex
+// 通用视频效果在split之前应用，所有档位共享；第i档的输出标签为[v{i}]
+// 返回值：额外输入参数、filter_complex、错误
+func (s *FFmpegService) buildLadderFilter(params model.TaskInputParams, inputPath string, renditions []model.Rendition, tempFiles *[]string) ([]string, string, error) {
+	// 画质修复在效果和缩放之前应用于源视频
+	graph, videoLabel := "", "0:v:0"
+	if params.Restore != nil {
+		restore, err := s.buildRestoreChain(params.Restore, inputPath)
+		if err != nil {
+			return nil, "", err
+		}
+		if restore != "" {
+			graph, videoLabel = fmt.Sprintf("[0:v:0]%s[restored]", restore), "restored"
+		}
+	}
+
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, inputPath, graph, videoLabel, 1, tempFiles)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// [src]split=N[s0][s1]...;[s0]scale=-2:1080[v0];...
+	var filters []string
+	if graph != "" {
+		filters = append(filters, graph)
+	}
+	split := fmt.Sprintf("[%s]split=%d", videoLabel, len(renditions))
+	for i := range renditions {
+		split += fmt.Sprintf("[s%d]", i)
+	}
+	filters = append(filters, split)
+	for i, r := range renditions {
+		filters = append(filters, fmt.Sprintf("[s%d]scale=-2:%d[v%d]", i, r.Height, i))
+	}
+
+	return effectInputs, strings.Join(filters, ";"), nil
+}
+
+// ladderVideoArgs 构建码率阶梯各档的视频编码参数
+// 每个切片起点强制关键帧，保证各档切片边界对齐
+func (s *FFmpegService) ladderVideoArgs(params model.TaskInputParams, renditions []model.Rendition, segmentDuration int) []string {
+	args := []string{
+		"-c:v", s.getVideoCodec(params.VideoCodec),
+		"-preset", "ultrafast",
+		"-pix_fmt", "yuv420p",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentDuration),
+		"-sc_threshold", "0",
+	}
+	if params.FPS > 0 {
+		args = append(args, "-r", fmt.Sprintf("%d", params.FPS))
+	}
+	for i, r := range renditions {
+		args = append(args,
+			fmt.Sprintf("-b:v:%d", i), r.VideoBitrate,
+			fmt.Sprintf("-maxrate:v:%d", i), r.VideoBitrate,
+			fmt.Sprintf("-bufsize:v:%d", i), doubleBitrate(r.VideoBitrate),
+		)
+	}
+	return args
+}
+
+// resolveRenditions 获取码率阶梯，去掉高于源视频的档位（至少保留最低一档）
+func (s *FFmpegService) resolveRenditions(renditions []model.Rendition, sourceHeight int) []model.Rendition {
+	if len(renditions) == 0 {
+		renditions = defaultRenditions
+	}
+
+	var result []model.Rendition
+	var lowest model.Rendition
+	for i, r := range renditions {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("%dp", r.Height)
+		}
+		if r.VideoBitrate == "" {
+			r.VideoBitrate = s.getVideoBitrate("")
+		}
+		if r.AudioBitrate == "" {
+			r.AudioBitrate = s.getAudioBitrate("")
+		}
+		if i == 0 || r.Height < lowest.Height {
+			lowest = r
+		}
+		if sourceHeight == 0 || r.Height <= sourceHeight {
+			result = append(result, r)
+		}
+	}
+
+	if len(result) == 0 {
+		result = append(result, lowest)
+	}
+	return result
+}
+
+// validateRenditions 验证码率阶梯
+func (s *FFmpegService) validateRenditions(renditions []model.Rendition) error {
+	names := make(map[string]bool)
+	for i, r := range renditions {
+		if r.Height <= 0 || r.Height%2 != 0 {
+			return fmt.Errorf("invalid rendition height at index %d: %d", i, r.Height)
+		}
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("%dp", r.Height)
+		}
+		if strings.ContainsAny(name, " /\\,:$%") {
+			return fmt.Errorf("invalid rendition name: %s", name)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate rendition name: %s", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// doubleBitrate 计算码率的两倍（用于bufsize），无法解析时原样返回
+func doubleBitrate(bitrate string) string {
+	unit := ""
+	num := bitrate
+	if n := len(bitrate); n > 0 && strings.ContainsAny(bitrate[n-1:], "kKmM") {
+		unit = bitrate[n-1:]
+		num = bitrate[:n-1]
+	}
+
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return bitrate
+	}
+	return strconv.FormatFloat(value*2, 'f', -1, 64) + unit
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// audioFormat 音频输出格式：muxer名称和默认编码器
+type audioFormat struct {
+	muxer string
+	codec string
+}
+
+// audioFormats 支持的音频输出格式（键为output_format，同时作为文件扩展名）
+var audioFormats = map[string]audioFormat{
+	"mp3":  {muxer: "mp3", codec: "libmp3lame"},
+	"aac":  {muxer: "adts", codec: "aac"},
+	"m4a":  {muxer: "ipod", codec: "aac"},
+	"opus": {muxer: "opus", codec: "libopus"},
+	"ogg":  {muxer: "ogg", codec: "libvorbis"},
+	"flac": {muxer: "flac", codec: "flac"},
+	"wav":  {muxer: "wav", codec: "pcm_s16le"},
+}
+
+// BuildAudioConvertCommand 构建音频提取/转换的ffmpeg命令
+// 输入可以是视频（提取音轨）或音频文件，输出为纯音频容器
+// 返回值：命令参数、总时长（秒，纯音频没有视频帧，按时长计算进度）、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildAudioConvertCommand(params model.TaskInputParams, outputPath string) ([]string, float64, []string, error) {
+	var tempFiles []string
+
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input file provided")
+	}
+
+	format := s.getAudioOutputFormat(params.OutputFormat)
+	container, ok := audioFormats[format]
+	if !ok {
+		return nil, 0, nil, fmt.Errorf("unsupported audio output format: %s", params.OutputFormat)
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input file failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+	if info.AudioCodec == "" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("input file has no audio stream")
+	}
+
+	codec := params.AudioCodec
+	if codec == "" {
+		codec = container.codec
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", localInputPath,
+		"-map", "0:a:0",
+		"-vn",
+		"-c:a", codec,
+	}
+
+	// 响度标准化（两遍loudnorm的第二遍）
+	if params.Loudness != nil {
+		if codec == "copy" {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("loudness normalization requires re-encoding, audio_codec cannot be copy")
+		}
+		sampleRate := params.SampleRate
+		if sampleRate == 0 {
+			sampleRate = info.SampleRate
+		}
+		loudnorm, err := s.buildLoudnormFilter(params.Loudness, localInputPath, sampleRate)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		args = append(args, "-af", loudnorm)
+	}
+
+	// 无损/PCM编码不使用码率参数
+	if codec != "copy" && codec != "flac" && codec != "pcm_s16le" {
+		args = append(args, "-b:a", s.getAudioBitrate(params.AudioBitrate))
+	}
+	if params.SampleRate > 0 {
+		args = append(args, "-ar", fmt.Sprintf("%d", params.SampleRate))
+	}
+	if params.Channels > 0 {
+		args = append(args, "-ac", fmt.Sprintf("%d", params.Channels))
+	}
+
+	args = append(args,
+		"-f", container.muxer,
+		"-y",
+		outputPath,
+	)
+
+	return args, info.Duration, tempFiles, nil
+}
+
+// getAudioOutputFormat 获取音频任务的输出格式，默认MP3
+func (s *FFmpegService) getAudioOutputFormat(format string) string {
+	if format == "" {
+		return "mp3"
+	}
+	return format
+}
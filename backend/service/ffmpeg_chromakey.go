@@ -0,0 +1,268 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 抠像参数默认值
+const (
+	chromaKeyDefaultColor      = "00FF00"
+	chromaKeyDefaultSimilarity = 0.1
+	chromaKeyDefaultBlend      = 0.05
+)
+
+// BuildChromaKeyCommand 构建绿幕抠像的ffmpeg命令
+// 前景（input_path）经chromakey抠除背景色、despill抑制溢色后缩放，叠加到适配为输出尺寸的背景图片/视频上
+// 输出时长与前景一致，背景视频较短时循环播放
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildChromaKeyCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	opts := params.ChromaKey
+	if opts == nil {
+		return nil, 0, nil, fmt.Errorf("no chroma_key options provided")
+	}
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no foreground video provided")
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download foreground video failed: %w", err)
+	}
+	localBackground, err := s.downloadInput(opts.Background, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("download background failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+	backgroundInfo, err := s.parser.GetMediaInfo(localBackground)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get background info failed: %w", err)
+	}
+
+	fps := float64(params.FPS)
+	if fps == 0 {
+		fps = info.FPS
+	}
+	if fps == 0 {
+		fps = 25
+	}
+	totalFrames := int(info.Duration * fps)
+
+	// 输出尺寸：优先使用参数，否则与背景一致
+	width, height := params.Width, params.Height
+	if width == 0 || height == 0 {
+		width, height = backgroundInfo.DisplaySize()
+		width, height = width&^1, height&^1
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", localInputPath,
+	}
+	backgroundIsImage := isImageInput(opts.Background)
+	if backgroundIsImage {
+		args = append(args, "-loop", "1", "-framerate", fmt.Sprintf("%.3f", fps))
+	} else {
+		args = append(args, "-stream_loop", "-1")
+	}
+	args = append(args, "-i", localBackground)
+
+	var filters []string
+
+	// 背景：默认铺满输出画面
+	backgroundFit := params.Fit
+	if backgroundFit == nil {
+		backgroundFit = &model.FitOptions{Mode: "cover"}
+	}
+	filters = append(filters, s.buildFitFilter(backgroundFit, "1:v", "bgfit", width, height))
+	filters = append(filters, fmt.Sprintf("[bgfit]fps=%.3f[bg]", fps))
+
+	// 前景：画质修复 -> 抠像 -> 溢色抑制 -> 缩放
+	color := strings.TrimPrefix(opts.Color, "#")
+	if color == "" {
+		color = chromaKeyDefaultColor
+	}
+	similarity, blend := opts.Similarity, opts.Blend
+	if similarity == 0 {
+		similarity = chromaKeyDefaultSimilarity
+	}
+	if blend == 0 {
+		blend = chromaKeyDefaultBlend
+	}
+	foreground := "[0:v]"
+	if params.Restore != nil {
+		// 先修复前景画质（降噪有助于得到干净的抠像边缘）
+		restore, err := s.buildRestoreChain(params.Restore, localInputPath)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if restore != "" {
+			foreground += restore + ","
+		}
+	}
+	foreground += fmt.Sprintf("chromakey=color=0x%s:similarity=%.3f:blend=%.3f", color, similarity, blend)
+	if opts.Despill > 0 {
+		foreground += fmt.Sprintf(",despill=type=%s:mix=%.2f", despillType(color), opts.Despill)
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	foreground += fmt.Sprintf(",scale=%d:-2,setsar=1,fps=%.3f[fg]", int(float64(width)*scale)&^1, fps)
+	filters = append(filters, foreground)
+
+	// 前景默认位于画面底部居中；shortest=1：以前景结束为准（背景为无限循环的图片或视频）
+	position := opts.Position
+	if position == "" {
+		position = "bottom"
+	}
+	x, y := s.overlayPosition(position, opts.Margin)
+	filters = append(filters, fmt.Sprintf("[bg][fg]overlay=x=%s:y=%s:shortest=1[keyed]", x, y))
+	graph := strings.Join(filters, ";")
+
+	// 通用视频效果（水印等），额外输入从索引2开始
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, localInputPath, graph, "keyed", 2, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 音频：默认取前景的音轨
+	foregroundAudio := info.AudioCodec != ""
+	backgroundAudio := !backgroundIsImage && backgroundInfo.AudioCodec != ""
+	audioMap := ""
+	switch opts.AudioSource {
+	case "none":
+	case "background":
+		if backgroundAudio {
+			audioMap = "1:a:0"
+		}
+	case "mix":
+		switch {
+		case foregroundAudio && backgroundAudio:
+			graph += ";[0:a:0][1:a:0]amix=inputs=2:duration=first:dropout_transition=0[aout]"
+			audioMap = "[aout]"
+		case foregroundAudio:
+			audioMap = "0:a:0"
+		case backgroundAudio:
+			audioMap = "1:a:0"
+		}
+	default: // foreground
+		if foregroundAudio {
+			audioMap = "0:a:0"
+		}
+	}
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", graph,
+		"-map", "["+videoLabel+"]",
+	)
+	if audioMap != "" {
+		args = append(args, "-map", audioMap)
+	}
+
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("chromakey requires re-encoding, video_codec cannot be copy")
+	}
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+	)
+	if audioMap != "" {
+		args = append(args,
+			"-c:a", s.getAudioCodec(params.AudioCodec),
+			"-b:a", s.getAudioBitrate(params.AudioBitrate),
+		)
+	}
+	args = append(args, subtitleArgs...)
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	// 背景循环播放，用前景时长限制输出
+	args = append(args,
+		"-t", fmt.Sprintf("%.3f", info.Duration),
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// despillType 根据抠除的颜色选择溢色抑制类型：蓝色分量占优时为blue，否则为green
+func despillType(color string) string {
+	g, _ := strconv.ParseUint(color[2:4], 16, 8)
+	b, _ := strconv.ParseUint(color[4:6], 16, 8)
+	if b > g {
+		return "blue"
+	}
+	return "green"
+}
+
+// validateChromaKey 验证绿幕抠像参数
+func (s *FFmpegService) validateChromaKey(opts *model.ChromaKeyOptions) error {
+	if opts.Background == "" {
+		return fmt.Errorf("chroma_key background is required")
+	}
+	if err := s.parser.ValidateFile(opts.Background); err != nil {
+		return fmt.Errorf("invalid chroma_key background: %w", err)
+	}
+
+	if opts.Color != "" && !hexColorPattern.MatchString(opts.Color) {
+		return fmt.Errorf("invalid chroma_key color: %s (expected #RRGGBB or RRGGBB)", opts.Color)
+	}
+	if opts.Similarity != 0 && (opts.Similarity < 0.01 || opts.Similarity > 1) {
+		return fmt.Errorf("chroma_key similarity must be between 0.01 and 1")
+	}
+	if opts.Blend < 0 || opts.Blend > 1 || opts.Despill < 0 || opts.Despill > 1 {
+		return fmt.Errorf("chroma_key blend and despill must be between 0 and 1")
+	}
+	if opts.Scale < 0 || opts.Scale > 1 {
+		return fmt.Errorf("chroma_key scale must be between 0 and 1")
+	}
+	if opts.Margin < 0 {
+		return fmt.Errorf("chroma_key margin must not be negative")
+	}
+
+	switch opts.Position {
+	case "", "top", "center", "bottom", "top_left", "top_right", "bottom_left", "bottom_right":
+	default:
+		return fmt.Errorf("invalid chroma_key position: %s", opts.Position)
+	}
+	switch opts.AudioSource {
+	case "", "foreground", "background", "mix", "none":
+	default:
+		return fmt.Errorf("invalid chroma_key audio_source: %s (expected foreground, background, mix or none)", opts.AudioSource)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// clipSegment 解析后的裁剪片段（绝对时间）
+type clipSegment struct {
+	start    float64
+	duration float64
+}
+
+// BuildClipCommand 构建视频裁剪的ffmpeg命令
+// fast模式：使用concat demuxer的inpoint/outpoint + 流复制，速度快但切点按关键帧对齐
+// accurate模式：每段独立seek作为输入，重新编码后用concat滤镜拼接，切点帧精确
+// 返回值：命令参数、总帧数（按裁剪后时长计算）、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildClipCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+	segments, err := s.resolveClipSegments(s.clipRanges(params), info.Duration)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+
+	// 进度按裁剪后的总时长计算，而不是整个源视频
+	var clipDuration float64
+	for _, seg := range segments {
+		clipDuration += seg.duration
+	}
+
+	var args []string
+	var fps float64
+	clipMode := params.ClipMode
+	if clipMode == "" {
+		clipMode = "fast"
+	}
+	// 流复制无法应用视频效果和编码参数，需要重新编码
+	if clipMode == "fast" && (s.hasVideoEffects(params) || clipNeedsEncode(params)) {
+		clipMode = "accurate"
+	}
+
+	switch clipMode {
+	case "fast":
+		fps = info.FPS
+		listFile, err := s.writeConcatList(localInputPath, segments)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		tempFiles = append(tempFiles, listFile)
+		args, err = s.buildFastClipArgs(params, listFile, outputPath, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+	case "accurate":
+		if params.VideoCodec == "copy" || params.AudioCodec == "copy" {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("accurate clip requires re-encoding, video_codec and audio_codec cannot be copy")
+		}
+		fps = float64(params.FPS)
+		if fps == 0 {
+			fps = info.FPS
+		}
+		hasAudio := info.AudioCodec != ""
+		args, err = s.buildAccurateClipArgs(params, localInputPath, segments, hasAudio, outputPath, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+	default:
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("unsupported clip mode: %s", params.ClipMode)
+	}
+
+	if fps == 0 {
+		fps = 25
+	}
+	totalFrames := int(clipDuration * fps)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// clipRanges 获取裁剪时间段列表，未设置clip_ranges时使用单段参数
+func (s *FFmpegService) clipRanges(params model.TaskInputParams) []model.ClipRange {
+	if len(params.ClipRanges) > 0 {
+		return params.ClipRanges
+	}
+	if params.ClipStart == 0 && params.ClipEnd == 0 && params.ClipDuration == 0 {
+		return nil
+	}
+	return []model.ClipRange{{
+		Start:    params.ClipStart,
+		End:      params.ClipEnd,
+		Duration: params.ClipDuration,
+	}}
+}
+
+// resolveClipSegments 将时间段换算为起点+时长，并按源视频时长截断
+func (s *FFmpegService) resolveClipSegments(ranges []model.ClipRange, sourceDuration float64) ([]clipSegment, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no clip range provided")
+	}
+
+	segments := make([]clipSegment, 0, len(ranges))
+	for i, r := range ranges {
+		end := sourceDuration
+		if r.Duration > 0 {
+			end = r.Start + r.Duration
+		} else if r.End > 0 {
+			end = r.End
+		}
+		if end > sourceDuration {
+			end = sourceDuration
+		}
+
+		if r.Start >= end {
+			return nil, fmt.Errorf("clip range at index %d is empty or beyond video duration (%.2fs)", i, sourceDuration)
+		}
+		segments = append(segments, clipSegment{start: r.Start, duration: end - r.Start})
+	}
+	return segments, nil
+}
+
+// writeConcatList 生成concat demuxer列表文件，每段使用inpoint/outpoint引用同一源文件
+func (s *FFmpegService) writeConcatList(inputPath string, segments []clipSegment) (string, error) {
+	var sb strings.Builder
+	for _, seg := range segments {
+		sb.WriteString(fmt.Sprintf("file '%s'\n", escapeConcatPath(inputPath)))
+		sb.WriteString(fmt.Sprintf("inpoint %.3f\n", seg.start))
+		sb.WriteString(fmt.Sprintf("outpoint %.3f\n", seg.start+seg.duration))
+	}
+	return s.writeTempFile("concat-*.txt", sb.String())
+}
+
+// buildFastClipArgs 流复制裁剪，不重新编码
+func (s *FFmpegService) buildFastClipArgs(params model.TaskInputParams, listFile string, outputPath string, tempFiles *[]string) ([]string, error) {
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-f", "concat",
+		"-safe", "0", // 允许绝对路径
+		"-i", listFile,
+	}
+
+	// 软字幕轨道（字幕时间以裁剪后的输出为准）
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), tempFiles)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-map", "0:v",
+		"-map", "0:a?",
+		"-c", "copy",
+		"-avoid_negative_ts", "make_zero",
+	)
+	args = append(args, subtitleArgs...)
+
+	args = append(args,
+		"-f", s.getOutputFormat(params.OutputFormat),
+		"-y",
+		outputPath,
+	)
+	return args, nil
+}
+
+// clipNeedsEncode 判断是否指定了编码参数（流复制时这些参数无效）
+func clipNeedsEncode(params model.TaskInputParams) bool {
+	return params.Width > 0 || params.Height > 0 || params.FPS > 0 ||
+		params.VideoBitrate != "" || params.AudioBitrate != "" ||
+		(params.VideoCodec != "" && params.VideoCodec != "copy") ||
+		(params.AudioCodec != "" && params.AudioCodec != "copy")
+}
+
+// buildAccurateClipArgs 帧精确裁剪：每段作为独立输入（输入端seek），concat滤镜拼接后重新编码
+func (s *FFmpegService) buildAccurateClipArgs(params model.TaskInputParams, inputPath string, segments []clipSegment, hasAudio bool, outputPath string, tempFiles *[]string) ([]string, error) {
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+	}
+
+	for _, seg := range segments {
+		args = append(args,
+			"-ss", fmt.Sprintf("%.3f", seg.start),
+			"-t", fmt.Sprintf("%.3f", seg.duration),
+			"-i", inputPath,
+		)
+	}
+
+	// 构建filter_complex：[0:v][0:a][1:v][1:a]concat=n=N:v=1:a=1[v][a]
+	var filter string
+	for i := range segments {
+		filter += fmt.Sprintf("[%d:v]", i)
+		if hasAudio {
+			filter += fmt.Sprintf("[%d:a]", i)
+		}
+	}
+	audioCount := 0
+	if hasAudio {
+		audioCount = 1
+	}
+	filter += fmt.Sprintf("concat=n=%d:v=1:a=%d[cv]", len(segments), audioCount)
+	if hasAudio {
+		filter += "[a]"
+	}
+
+	// 拼接后依次应用画质修复和缩放
+	var videoFilters []string
+	if params.Restore != nil {
+		restore, err := s.buildRestoreChain(params.Restore, inputPath)
+		if err != nil {
+			return nil, err
+		}
+		if restore != "" {
+			videoFilters = append(videoFilters, restore)
+		}
+	}
+	if scale := s.buildScaleFilter(params.Width, params.Height); scale != "" {
+		videoFilters = append(videoFilters, scale)
+	}
+	if len(videoFilters) == 0 {
+		videoFilters = append(videoFilters, "null")
+	}
+	filter += fmt.Sprintf(";[cv]%s[v]", strings.Join(videoFilters, ","))
+
+	// 通用视频效果（水印等），额外输入排在所有片段之后
+	effectInputs, filter, videoLabel, err := s.appendVideoEffects(params, inputPath, filter, "v", len(segments), tempFiles)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), tempFiles)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", filter,
+		"-map", "["+videoLabel+"]",
+	)
+	if hasAudio {
+		args = append(args, "-map", "[a]")
+	}
+
+	args = append(args,
+		"-c:v", s.getVideoCodec(params.VideoCodec),
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+	)
+	if params.FPS > 0 {
+		args = append(args, "-r", fmt.Sprintf("%d", params.FPS))
+	}
+	if hasAudio {
+		args = append(args,
+			"-c:a", s.getAudioCodec(params.AudioCodec),
+			"-b:a", s.getAudioBitrate(params.AudioBitrate),
+		)
+	}
+	args = append(args, subtitleArgs...)
+
+	args = append(args,
+		"-f", s.getOutputFormat(params.OutputFormat),
+		"-y",
+		outputPath,
+	)
+
+	return args, nil
+}
+
+// escapeConcatPath 转义concat列表中的单引号
+func escapeConcatPath(path string) string {
+	return strings.ReplaceAll(path, "'", `'\''`)
+}
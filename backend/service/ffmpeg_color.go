@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// colorLooks 内置调色风格对应的滤镜链
+var colorLooks = map[string]string{
+	"warm":      "colorbalance=rs=0.08:gs=0.02:bs=-0.08:rm=0.06:bm=-0.06",
+	"cool":      "colorbalance=rs=-0.08:bs=0.08:rm=-0.05:bm=0.06",
+	"vintage":   "curves=preset=vintage,eq=saturation=0.85",
+	"cinematic": "curves=preset=medium_contrast,colorbalance=rs=-0.1:bs=0.1:rh=0.08:bh=-0.08", // 青色阴影、橙色高光
+	"vivid":     "vibrance=intensity=0.35,eq=contrast=1.05",
+	"bw":        "hue=s=0",
+}
+
+// buildColorFilter 构建调色滤镜：[in] -> [out]，依次应用内置风格、LUT和eq基础校色
+// LUT文件与其他输入一样先下载到本地，lut3d按扩展名识别.cube/.3dl格式
+func (s *FFmpegService) buildColorFilter(color *model.ColorOptions, in, out string, tempFiles *[]string) (string, error) {
+	var filters []string
+
+	if color.Look != "" {
+		filters = append(filters, colorLooks[color.Look])
+	}
+
+	if color.LUTPath != "" {
+		localPath, err := s.downloadInput(color.LUTPath, tempFiles)
+		if err != nil {
+			return "", fmt.Errorf("download lut failed: %w", err)
+		}
+		filters = append(filters, fmt.Sprintf("lut3d=file='%s'", escapeFilterPath(localPath)))
+	}
+
+	var eq []string
+	if color.Brightness != 0 {
+		eq = append(eq, fmt.Sprintf("brightness=%.3f", color.Brightness))
+	}
+	if color.Contrast != 0 {
+		eq = append(eq, fmt.Sprintf("contrast=%.3f", color.Contrast))
+	}
+	if color.Saturation != 0 {
+		eq = append(eq, fmt.Sprintf("saturation=%.3f", color.Saturation))
+	}
+	if color.Gamma != 0 {
+		eq = append(eq, fmt.Sprintf("gamma=%.3f", color.Gamma))
+	}
+	if len(eq) > 0 {
+		filters = append(filters, "eq="+strings.Join(eq, ":"))
+	}
+
+	if len(filters) == 0 {
+		filters = append(filters, "null")
+	}
+	return fmt.Sprintf("[%s]%s[%s]", in, strings.Join(filters, ","), out), nil
+}
+
+// validateColor 验证调色参数
+func (s *FFmpegService) validateColor(color *model.ColorOptions) error {
+	if color.Look != "" {
+		if _, ok := colorLooks[color.Look]; !ok {
+			return fmt.Errorf("invalid color look: %s (expected warm, cool, vintage, cinematic, vivid or bw)", color.Look)
+		}
+	}
+
+	if color.LUTPath != "" {
+		// LUT不是媒体文件，无法用ffprobe验证，只检查格式
+		switch strings.ToLower(filepath.Ext(strings.SplitN(color.LUTPath, "?", 2)[0])) {
+		case ".cube", ".3dl":
+		default:
+			return fmt.Errorf("invalid lut file: %s (expected .cube or .3dl)", color.LUTPath)
+		}
+	}
+
+	if color.Brightness < -1 || color.Brightness > 1 {
+		return fmt.Errorf("color brightness must be between -1 and 1")
+	}
+	if color.Contrast < 0 || color.Contrast > 3 {
+		return fmt.Errorf("color contrast must be between 0 and 3")
+	}
+	if color.Saturation < 0 || color.Saturation > 3 {
+		return fmt.Errorf("color saturation must be between 0 and 3")
+	}
+	if color.Gamma != 0 && (color.Gamma < 0.1 || color.Gamma > 10) {
+		return fmt.Errorf("color gamma must be between 0.1 and 10")
+	}
+	return nil
+}
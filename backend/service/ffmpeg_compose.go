@@ -0,0 +1,368 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// 画面合成的输入数量限制和默认值
+const (
+	composeMinInputs     = 2
+	composeMaxInputs     = 9
+	composeDefaultScale  = 0.3
+	composeDefaultWidth  = 1280
+	composeDefaultHeight = 720
+)
+
+// composeImageExts 按静态图片处理（-loop 1）的文件扩展名
+var composeImageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".bmp": true, ".webp": true,
+}
+
+// isImageInput 根据扩展名判断输入是否为静态图片（URL去掉查询参数和片段后判断）
+func isImageInput(path string) bool {
+	path = strings.SplitN(path, "?", 2)[0]
+	path = strings.SplitN(path, "#", 2)[0]
+	return composeImageExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// composeColor 将#RRGGBB转换为ffmpeg颜色格式，为空时使用默认颜色
+func composeColor(color, fallback string) string {
+	if color == "" {
+		return fallback
+	}
+	return "0x" + strings.TrimPrefix(color, "#")
+}
+
+// BuildComposeCommand 构建画面合成（画中画/多宫格）的ffmpeg命令
+// pip：第一个输入适配为主画面，其余输入缩放为小窗（可加边框和圆角）叠加到锚点位置；
+// grid：所有输入适配到格子尺寸后用xstack排列为N×M宫格
+// 输出时长取最长的输入（或duration_input指定的输入），图片输入循环到该时长
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildComposeCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	opts := params.Compose
+	if opts == nil {
+		return nil, 0, nil, fmt.Errorf("no compose options provided")
+	}
+	if len(opts.Inputs) < composeMinInputs || len(opts.Inputs) > composeMaxInputs {
+		return nil, 0, nil, fmt.Errorf("compose requires %d-%d inputs", composeMinInputs, composeMaxInputs)
+	}
+
+	// 下载所有输入并获取媒体信息（图片没有时长和音频）
+	localPaths := make([]string, 0, len(opts.Inputs))
+	infos := make([]*ffmpeg.MediaInfo, 0, len(opts.Inputs))
+	for i, input := range opts.Inputs {
+		localPath, err := s.downloadInput(input.Path, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("download compose input %d failed: %w", i, err)
+		}
+
+		info := &ffmpeg.MediaInfo{}
+		if !isImageInput(input.Path) {
+			info, err = s.parser.GetMediaInfo(localPath)
+			if err != nil {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, fmt.Errorf("get media info of compose input %d failed: %w", i, err)
+			}
+		}
+
+		localPaths = append(localPaths, localPath)
+		infos = append(infos, info)
+	}
+
+	// 输出时长：指定输入的时长，否则取最长的输入
+	var duration float64
+	if opts.DurationInput != nil {
+		duration = infos[*opts.DurationInput].Duration
+	} else {
+		for _, info := range infos {
+			duration = math.Max(duration, info.Duration)
+		}
+	}
+	if duration == 0 {
+		// 全部为图片时使用image_duration
+		duration = params.ImageDuration
+		if duration == 0 {
+			duration = defaultImageDuration
+		}
+	}
+
+	width, height := params.Width, params.Height
+	if width == 0 || height == 0 {
+		width, height = composeDefaultWidth, composeDefaultHeight
+	}
+	fps := params.FPS
+	if fps == 0 {
+		fps = 25
+	}
+	totalFrames := int(duration * float64(fps))
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+	}
+	for i, localPath := range localPaths {
+		if isImageInput(opts.Inputs[i].Path) {
+			args = append(args,
+				"-loop", "1",
+				"-framerate", fmt.Sprintf("%d", fps),
+				"-t", fmt.Sprintf("%.3f", duration),
+			)
+		}
+		args = append(args, "-i", localPath)
+	}
+
+	// 主画面和格子默认完整显示（contain），空白处填充背景色
+	fit := params.Fit
+	if fit == nil {
+		fit = &model.FitOptions{Mode: "contain"}
+		if opts.Background != "" {
+			fit.Background = opts.Background
+		}
+	}
+
+	var graph string
+	if opts.Layout == "grid" {
+		graph = s.buildComposeGrid(opts, fit, width, height, fps)
+	} else {
+		graph = s.buildComposePIP(opts, fit, width, height, fps)
+	}
+	videoLabel := "composed"
+
+	// 通用视频效果（水印等）
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, "", graph, videoLabel, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 音频：混合所有输入的音轨，或取指定输入的音轨
+	audioMap := ""
+	if opts.AudioMix {
+		var audioLabels []string
+		for i, info := range infos {
+			if info.AudioCodec != "" {
+				audioLabels = append(audioLabels, fmt.Sprintf("[%d:a]", i))
+			}
+		}
+		switch len(audioLabels) {
+		case 0:
+		case 1:
+			audioMap = strings.Trim(audioLabels[0], "[]")
+		default:
+			// normalize=0 保持各路原始音量，不按输入数量衰减
+			graph += fmt.Sprintf(";%samix=inputs=%d:duration=longest:dropout_transition=0:normalize=0[aout]",
+				strings.Join(audioLabels, ""), len(audioLabels))
+			audioMap = "[aout]"
+		}
+	} else if infos[opts.AudioInput].AudioCodec != "" {
+		audioMap = fmt.Sprintf("%d:a:0", opts.AudioInput)
+	}
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", graph,
+		"-map", "["+videoLabel+"]",
+	)
+	if audioMap != "" {
+		args = append(args, "-map", audioMap)
+	}
+
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("compose requires re-encoding, video_codec cannot be copy")
+	}
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+		"-r", fmt.Sprintf("%d", fps),
+	)
+	if audioMap != "" {
+		args = append(args,
+			"-c:a", s.getAudioCodec(params.AudioCodec),
+			"-b:a", s.getAudioBitrate(params.AudioBitrate),
+		)
+	}
+	args = append(args, subtitleArgs...)
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	args = append(args,
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// buildComposePIP 构建画中画滤镜图：主画面铺满输出，其余输入依次作为小窗叠加，输出标签为[composed]
+func (s *FFmpegService) buildComposePIP(opts *model.ComposeOptions, fit *model.FitOptions, width, height, fps int) string {
+	var filters []string
+
+	filters = append(filters, s.buildFitFilter(fit, "0:v", "main", width, height))
+	filters = append(filters, fmt.Sprintf("[main]fps=%d[base0]", fps))
+
+	margin := opts.Margin
+	if margin == 0 {
+		margin = 20
+	}
+
+	base := "base0"
+	for i := 1; i < len(opts.Inputs); i++ {
+		input := opts.Inputs[i]
+		scale := input.Scale
+		if scale == 0 {
+			scale = composeDefaultScale
+		}
+
+		// 小窗：按宽度比例缩放，再加边框和圆角
+		window := fmt.Sprintf("[%d:v]fps=%d,scale=%d:-2,setsar=1", i, fps, int(float64(width)*scale)&^1)
+		if opts.Border > 0 {
+			window += fmt.Sprintf(",pad=iw+%d:ih+%d:%d:%d:color=%s",
+				opts.Border*2, opts.Border*2, opts.Border, opts.Border, composeColor(opts.BorderColor, "white"))
+		}
+		if opts.Radius > 0 {
+			window += ",format=rgba," + roundedCornerFilter(opts.Radius)
+		}
+		filters = append(filters, fmt.Sprintf("%s[pip%d]", window, i))
+
+		out := fmt.Sprintf("base%d", i)
+		if i == len(opts.Inputs)-1 {
+			out = "composed"
+		}
+		x, y := s.overlayPosition(input.Position, margin)
+		filters = append(filters, fmt.Sprintf("[%s][pip%d]overlay=x=%s:y=%s[%s]", base, i, x, y, out))
+		base = out
+	}
+
+	return strings.Join(filters, ";")
+}
+
+// roundedCornerFilter 构建圆角遮罩：四个角半径为radius的圆外区域alpha置0
+func roundedCornerFilter(radius int) string {
+	r := fmt.Sprintf("%d", radius)
+	inCorner := "gt(abs(W/2-X),W/2-" + r + ")*gt(abs(H/2-Y),H/2-" + r + ")"
+	outside := "gt(hypot(abs(W/2-X)-(W/2-" + r + "),abs(H/2-Y)-(H/2-" + r + "))," + r + ")"
+	return fmt.Sprintf("geq=r='r(X,Y)':g='g(X,Y)':b='b(X,Y)':a='if(%s*%s,0,alpha(X,Y))'", inCorner, outside)
+}
+
+// composeGridSize 计算宫格的列数和行数，未指定时取接近正方形的排列
+func composeGridSize(opts *model.ComposeOptions) (int, int) {
+	n := len(opts.Inputs)
+	columns, rows := opts.Columns, opts.Rows
+	if columns == 0 {
+		if rows > 0 {
+			columns = (n + rows - 1) / rows
+		} else {
+			columns = int(math.Ceil(math.Sqrt(float64(n))))
+		}
+	}
+	if rows == 0 {
+		rows = (n + columns - 1) / columns
+	}
+	return columns, rows
+}
+
+// buildComposeGrid 构建多宫格滤镜图：每个输入适配到格子尺寸后用xstack按行排列，输出标签为[composed]
+func (s *FFmpegService) buildComposeGrid(opts *model.ComposeOptions, fit *model.FitOptions, width, height, fps int) string {
+	var filters []string
+
+	columns, rows := composeGridSize(opts)
+	cellWidth := ((width - opts.Gap*(columns-1)) / columns) &^ 1
+	cellHeight := ((height - opts.Gap*(rows-1)) / rows) &^ 1
+
+	var cells, layout []string
+	for i := range opts.Inputs {
+		cell := fmt.Sprintf("cell%d", i)
+		filters = append(filters, s.buildFitFilter(fit, fmt.Sprintf("%d:v", i), cell+"fit", cellWidth, cellHeight))
+		filters = append(filters, fmt.Sprintf("[%sfit]fps=%d[%s]", cell, fps, cell))
+		cells = append(cells, "["+cell+"]")
+
+		col, row := i%columns, i/columns
+		layout = append(layout, fmt.Sprintf("%d_%d", col*(cellWidth+opts.Gap), row*(cellHeight+opts.Gap)))
+	}
+
+	// 格子尺寸取偶数后可能略小于输出，居中补齐到目标尺寸
+	background := composeColor(opts.Background, "black")
+	filters = append(filters, fmt.Sprintf("%sxstack=inputs=%d:layout=%s:fill=%s,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=%s[composed]",
+		strings.Join(cells, ""), len(cells), strings.Join(layout, "|"), background, width, height, background))
+
+	return strings.Join(filters, ";")
+}
+
+// validateCompose 验证画面合成参数
+func (s *FFmpegService) validateCompose(opts *model.ComposeOptions) error {
+	n := len(opts.Inputs)
+	if n < composeMinInputs || n > composeMaxInputs {
+		return fmt.Errorf("compose requires %d-%d inputs, got %d", composeMinInputs, composeMaxInputs, n)
+	}
+	for i, input := range opts.Inputs {
+		if input.Path == "" {
+			return fmt.Errorf("compose input %d: path is required", i)
+		}
+		if err := s.parser.ValidateFile(input.Path); err != nil {
+			return fmt.Errorf("invalid compose input %d: %w", i, err)
+		}
+		switch input.Position {
+		case "", "top", "center", "bottom", "top_left", "top_right", "bottom_left", "bottom_right":
+		default:
+			return fmt.Errorf("invalid compose input %d position: %s", i, input.Position)
+		}
+		if input.Scale < 0 || input.Scale > 1 {
+			return fmt.Errorf("compose input %d scale must be between 0 and 1", i)
+		}
+	}
+
+	switch opts.Layout {
+	case "", "pip":
+	case "grid":
+		if opts.Columns < 0 || opts.Rows < 0 || opts.Gap < 0 {
+			return fmt.Errorf("compose columns, rows and gap must not be negative")
+		}
+		if columns, rows := composeGridSize(opts); columns*rows < n {
+			return fmt.Errorf("compose grid %dx%d cannot hold %d inputs", columns, rows, n)
+		}
+	default:
+		return fmt.Errorf("invalid compose layout: %s", opts.Layout)
+	}
+
+	if opts.Margin < 0 || opts.Border < 0 || opts.Radius < 0 {
+		return fmt.Errorf("compose margin, border and radius must not be negative")
+	}
+	for _, color := range []string{opts.Background, opts.BorderColor} {
+		if color != "" && !hexColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid compose color: %s (expected #RRGGBB or RRGGBB)", color)
+		}
+	}
+	if opts.AudioInput < 0 || opts.AudioInput >= n {
+		return fmt.Errorf("compose audio_input %d out of range", opts.AudioInput)
+	}
+	if opts.DurationInput != nil && (*opts.DurationInput < 0 || *opts.DurationInput >= n) {
+		return fmt.Errorf("compose duration_input %d out of range", *opts.DurationInput)
+	}
+	return nil
+}
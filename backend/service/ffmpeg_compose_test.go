@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+func TestComposeGridSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   int
+		columns  int
+		rows     int
+		wantCols int
+		wantRows int
+	}{
+		{"two inputs", 2, 0, 0, 2, 1},
+		{"four inputs", 4, 0, 0, 2, 2},
+		{"five inputs", 5, 0, 0, 3, 2},
+		{"nine inputs", 9, 0, 0, 3, 3},
+		{"fixed columns", 5, 1, 0, 1, 5},
+		{"fixed rows", 5, 0, 2, 3, 2},
+		{"fixed both", 3, 2, 2, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &model.ComposeOptions{
+				Inputs:  make([]model.ComposeInput, tt.inputs),
+				Columns: tt.columns,
+				Rows:    tt.rows,
+			}
+			cols, rows := composeGridSize(opts)
+			if cols != tt.wantCols || rows != tt.wantRows {
+				t.Errorf("composeGridSize() = %dx%d, want %dx%d", cols, rows, tt.wantCols, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestIsImageInput(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/data/uploads/bg.png", true},
+		{"/data/uploads/BG.JPG", true},
+		{"/data/uploads/clip.mp4", false},
+		{"https://cdn.example.com/x.png?sig=abc.mp4", true},
+		{"https://cdn.example.com/x.webp#frame", true},
+		{"https://cdn.example.com/x.mp4?format=.png", false},
+	}
+
+	for _, tt := range tests {
+		if got := isImageInput(tt.path); got != tt.want {
+			t.Errorf("isImageInput(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
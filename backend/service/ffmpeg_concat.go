@@ -0,0 +1,320 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// 拼接时统一的音频格式
+const (
+	concatSampleRate    = 44100
+	concatChannelLayout = "stereo"
+)
+
+// BuildConcatCommand 构建视频拼接的ffmpeg命令
+// 所有输入编码参数一致且无需重新编码时，使用concat demuxer流复制；
+// 否则在filter_complex中统一分辨率（scale+pad）、帧率（fps）、采样率（aresample）后拼接，可选交叉淡化
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildConcatCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	if len(params.InputPaths) < 2 {
+		return nil, 0, nil, fmt.Errorf("at least 2 input videos are required")
+	}
+
+	// 下载所有视频到本地并获取媒体信息
+	localPaths := make([]string, 0, len(params.InputPaths))
+	infos := make([]*ffmpeg.MediaInfo, 0, len(params.InputPaths))
+	for _, inputPath := range params.InputPaths {
+		localPath, err := s.downloadInput(inputPath, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("download video %s failed: %w", inputPath, err)
+		}
+
+		info, err := s.parser.GetMediaInfo(localPath)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("get media info of %s failed: %w", inputPath, err)
+		}
+
+		localPaths = append(localPaths, localPath)
+		infos = append(infos, info)
+	}
+
+	// 输出尺寸和帧率：优先使用参数，否则沿用第一个视频
+	width, height := concatOutputSize(params, infos[0])
+	fps := float64(params.FPS)
+	if fps == 0 {
+		fps = infos[0].FPS
+	}
+	if fps == 0 {
+		fps = 25
+	}
+
+	// 计算总时长（交叉淡化会产生重叠）
+	var totalDuration float64
+	for _, info := range infos {
+		totalDuration += info.Duration
+	}
+	if params.CrossfadeDur > 0 {
+		totalDuration -= float64(len(infos)-1) * params.CrossfadeDur
+	}
+	totalFrames := int(totalDuration * fps)
+
+	// 兼容时走流复制快速路径
+	if s.canStreamCopyConcat(params, infos) {
+		listFile, err := s.writeConcatFileList(localPaths)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		tempFiles = append(tempFiles, listFile)
+
+		args := []string{
+			"-loglevel", "info",
+			"-stats",
+			"-f", "concat",
+			"-safe", "0",
+			"-i", listFile,
+		}
+
+		subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		args = append(args, subtitleInputs...)
+
+		args = append(args,
+			"-map", "0:v",
+			"-map", "0:a?",
+			"-c", "copy",
+		)
+		args = append(args, subtitleArgs...)
+		args = append(args,
+			"-f", s.getOutputFormat(params.OutputFormat),
+			"-y",
+			outputPath,
+		)
+		return args, totalFrames, tempFiles, nil
+	}
+
+	if params.CrossfadeDur > 0 {
+		for i, info := range infos {
+			if info.Duration <= params.CrossfadeDur {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, fmt.Errorf("video at index %d (%.2fs) is shorter than crossfade duration", i, info.Duration)
+			}
+		}
+	}
+
+	// 只要有一个输入带音频，就为无音频的输入补静音，保证concat/acrossfade对齐
+	hasAudio := false
+	for _, info := range infos {
+		if info.AudioCodec != "" {
+			hasAudio = true
+			break
+		}
+	}
+
+	// 尺寸/帧率/音频格式不一致时必须经过滤镜重新编码
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	audioCodec := s.getAudioCodec(params.AudioCodec)
+	if videoCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("concat requires re-encoding, video_codec cannot be copy")
+	}
+	if hasAudio && audioCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("concat requires re-encoding, audio_codec cannot be copy")
+	}
+
+	filterComplex := s.buildConcatFilter(infos, width, height, fps, params.CrossfadeDur, hasAudio)
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+	}
+	for _, localPath := range localPaths {
+		args = append(args, "-i", localPath)
+	}
+
+	// 通用视频效果（水印等），额外输入排在所有视频之后
+	effectInputs, filterComplex, videoLabel, err := s.appendVideoEffects(params, "", filterComplex, "v", len(localPaths), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", filterComplex,
+		"-map", "["+videoLabel+"]",
+	)
+	if hasAudio {
+		args = append(args, "-map", "[a]")
+	}
+
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+	)
+	if hasAudio {
+		args = append(args,
+			"-c:a", audioCodec,
+			"-b:a", s.getAudioBitrate(params.AudioBitrate),
+		)
+	}
+	args = append(args, subtitleArgs...)
+
+	args = append(args,
+		"-f", s.getOutputFormat(params.OutputFormat),
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// concatOutputSize 计算拼接输出尺寸
+// 未指定的一边按第一个视频的显示宽高比推算，都未指定时沿用第一个视频的显示尺寸（yuv420p要求宽高为偶数）
+func concatOutputSize(params model.TaskInputParams, first *ffmpeg.MediaInfo) (int, int) {
+	firstWidth, firstHeight := first.DisplaySize()
+	width, height := params.Width, params.Height
+	switch {
+	case width > 0 && height > 0:
+	case width > 0 && firstWidth > 0:
+		height = int(math.Round(float64(width)*float64(firstHeight)/float64(firstWidth)/2)) * 2
+	case height > 0 && firstHeight > 0:
+		width = int(math.Round(float64(height)*float64(firstWidth)/float64(firstHeight)/2)) * 2
+	default:
+		width, height = firstWidth, firstHeight
+	}
+	return width &^ 1, height &^ 1
+}
+
+// canStreamCopyConcat 判断是否可以直接流复制拼接
+// 要求：不需要交叉淡化、不需要改变编码/尺寸/帧率，且所有输入的音视频参数一致
+func (s *FFmpegService) canStreamCopyConcat(params model.TaskInputParams, infos []*ffmpeg.MediaInfo) bool {
+	if params.CrossfadeDur > 0 || s.hasVideoEffects(params) {
+		return false
+	}
+	if params.VideoCodec != "" && params.VideoCodec != "copy" {
+		return false
+	}
+	if params.AudioCodec != "" && params.AudioCodec != "copy" {
+		return false
+	}
+
+	first := infos[0]
+	firstWidth, firstHeight := first.DisplaySize()
+	if (params.Width > 0 && params.Width != firstWidth) || (params.Height > 0 && params.Height != firstHeight) {
+		return false
+	}
+	if params.FPS > 0 && math.Abs(float64(params.FPS)-first.FPS) > 0.01 {
+		return false
+	}
+
+	for _, info := range infos[1:] {
+		if info.VideoCodec != first.VideoCodec ||
+			info.Width != first.Width ||
+			info.Height != first.Height ||
+			info.Rotation != first.Rotation ||
+			math.Abs(info.FPS-first.FPS) > 0.01 ||
+			info.AudioCodec != first.AudioCodec ||
+			info.SampleRate != first.SampleRate ||
+			info.Channels != first.Channels {
+			return false
+		}
+	}
+	return true
+}
+
+// writeConcatFileList 生成concat demuxer列表文件
+func (s *FFmpegService) writeConcatFileList(paths []string) (string, error) {
+	var sb strings.Builder
+	for _, path := range paths {
+		sb.WriteString(fmt.Sprintf("file '%s'\n", escapeConcatPath(path)))
+	}
+	return s.writeTempFile("concat-*.txt", sb.String())
+}
+
+// buildConcatFilter 构建拼接的filter_complex
+// 每个输入先统一为相同的尺寸、像素格式、帧率和音频格式，再concat或链式xfade/acrossfade
+func (s *FFmpegService) buildConcatFilter(infos []*ffmpeg.MediaInfo, width, height int, fps float64, crossfadeDur float64, hasAudio bool) string {
+	var filters []string
+
+	for i, info := range infos {
+		// 等比缩放后居中补黑边，避免画面变形
+		filters = append(filters, fmt.Sprintf(
+			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%.3f,format=yuv420p,settb=AVTB[v%d]",
+			i, width, height, width, height, fps, i))
+
+		if !hasAudio {
+			continue
+		}
+		if info.AudioCodec != "" {
+			filters = append(filters, fmt.Sprintf(
+				"[%d:a]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s[a%d]",
+				i, concatSampleRate, concatChannelLayout, i))
+		} else {
+			// 无音频的输入补一段等长静音
+			filters = append(filters, fmt.Sprintf(
+				"anullsrc=channel_layout=%s:sample_rate=%d,atrim=duration=%.3f,aformat=sample_fmts=fltp[a%d]",
+				concatChannelLayout, concatSampleRate, info.Duration, i))
+		}
+	}
+
+	if crossfadeDur <= 0 {
+		var inputs string
+		for i := range infos {
+			inputs += fmt.Sprintf("[v%d]", i)
+			if hasAudio {
+				inputs += fmt.Sprintf("[a%d]", i)
+			}
+		}
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=1[v][a]", inputs, len(infos)))
+		} else {
+			filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", inputs, len(infos)))
+		}
+		return strings.Join(filters, ";")
+	}
+
+	// 链式交叉淡化：第k次xfade的offset = 前k段总时长 - k*淡化时长
+	var elapsed float64
+	prevV, prevA := "v0", "a0"
+	for i := 1; i < len(infos); i++ {
+		elapsed += infos[i-1].Duration
+		offset := elapsed - float64(i)*crossfadeDur
+
+		outV, outA := fmt.Sprintf("vx%d", i), fmt.Sprintf("ax%d", i)
+		if i == len(infos)-1 {
+			outV, outA = "v", "a"
+		}
+
+		filters = append(filters, fmt.Sprintf("[%s][v%d]xfade=transition=fade:duration=%.3f:offset=%.3f[%s]",
+			prevV, i, crossfadeDur, offset, outV))
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("[%s][a%d]acrossfade=d=%.3f[%s]", prevA, i, crossfadeDur, outA))
+		}
+		prevV, prevA = outV, outA
+	}
+
+	return strings.Join(filters, ";")
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+func TestConcatOutputSize(t *testing.T) {
+	landscape := &ffmpeg.MediaInfo{Width: 1920, Height: 1080}
+	// 竖拍手机视频：编码尺寸为横向，旋转90度显示
+	rotated := &ffmpeg.MediaInfo{Width: 1920, Height: 1080, Rotation: 90}
+
+	tests := []struct {
+		name       string
+		width      int
+		height     int
+		first      *ffmpeg.MediaInfo
+		wantWidth  int
+		wantHeight int
+	}{
+		{"first clip size", 0, 0, landscape, 1920, 1080},
+		{"rotated first clip", 0, 0, rotated, 1080, 1920},
+		{"both given", 640, 480, landscape, 640, 480},
+		{"width only", 1280, 0, landscape, 1280, 720},
+		{"height only", 0, 480, landscape, 854, 480},
+		{"width only rotated", 720, 0, rotated, 720, 1280},
+		{"odd size", 641, 0, landscape, 640, 360},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := model.TaskInputParams{Width: tt.width, Height: tt.height}
+			width, height := concatOutputSize(params, tt.first)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("concatOutputSize() = %dx%d, want %dx%d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// DASHManifest DASH清单文件名
+const DASHManifest = "manifest.mpd"
+
+// buildDASHArgs 构建MPEG-DASH多码率打包参数
+// 视频按码率阶梯生成多个representation，音频单独一个adaptation set（每个不同的档位音频码率一个representation），切片为fMP4（CMAF兼容）
+func (s *FFmpegService) buildDASHArgs(params model.TaskInputParams, inputPath string, info *ffmpeg.MediaInfo, outputPath string, tempFiles *[]string) ([]string, error) {
+	var opts model.DASHOptions
+	if params.DASH != nil {
+		opts = *params.DASH
+	}
+	renditions := s.resolveRenditions(opts.Renditions, info.Height)
+
+	segmentDuration := opts.SegmentDuration
+	if segmentDuration == 0 {
+		segmentDuration = 4
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", inputPath,
+	}
+
+	effectInputs, filterComplex, err := s.buildLadderFilter(params, inputPath, renditions, tempFiles)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, effectInputs...)
+	args = append(args, "-filter_complex", filterComplex)
+
+	for i := range renditions {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i))
+	}
+	hasAudio := info.AudioCodec != ""
+	var audioBitrates []string
+	if hasAudio {
+		audioBitrates = distinctAudioBitrates(renditions)
+		for range audioBitrates {
+			args = append(args, "-map", "0:a:0")
+		}
+	}
+
+	args = append(args, s.ladderVideoArgs(params, renditions, segmentDuration)...)
+	adaptationSets := "id=0,streams=v"
+	if hasAudio {
+		args = append(args, "-c:a", s.getAudioCodec(params.AudioCodec))
+		for i, bitrate := range audioBitrates {
+			args = append(args, fmt.Sprintf("-b:a:%d", i), bitrate)
+		}
+		adaptationSets += " id=1,streams=a"
+	}
+
+	args = append(args,
+		"-f", "dash",
+		"-seg_duration", strconv.Itoa(segmentDuration),
+		"-dash_segment_type", "mp4",
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-init_seg_name", "init-$RepresentationID$.m4s",
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+		"-adaptation_sets", adaptationSets,
+	)
+	if opts.CMAF {
+		// 同时输出引用相同fMP4切片的HLS播放列表（master.m3u8）
+		args = append(args,
+			"-hls_playlist", "1",
+			"-hls_master_name", HLSMasterPlaylist,
+		)
+	}
+
+	args = append(args,
+		"-y",
+		outputPath,
+	)
+
+	return args, nil
+}
+
+// distinctAudioBitrates 按档位顺序去重音频码率，多个档位共用同一码率时只编码一路音频
+func distinctAudioBitrates(renditions []model.Rendition) []string {
+	seen := make(map[string]bool)
+	var bitrates []string
+	for _, r := range renditions {
+		if !seen[r.AudioBitrate] {
+			seen[r.AudioBitrate] = true
+			bitrates = append(bitrates, r.AudioBitrate)
+		}
+	}
+	return bitrates
+}
+
+// validateDASH 验证DASH参数
+func (s *FFmpegService) validateDASH(opts *model.DASHOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.SegmentDuration < 0 {
+		return fmt.Errorf("dash segment_duration must not be negative")
+	}
+	return s.validateRenditions(opts.Renditions)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// hasVideoEffects 判断是否需要应用通用视频效果（需要重新编码视频）
+func (s *FFmpegService) hasVideoEffects(params model.TaskInputParams) bool {
+	return params.Restore != nil ||
+		params.Color != nil ||
+		presetNeedsReframe(params) ||
+		params.Watermark != nil ||
+		len(params.TextOverlays) > 0 ||
+		(params.Subtitle != nil && subtitleMode(params.Subtitle) == "burn")
+}
+
+// countInputs 统计命令参数中已有的输入数量，用于确定下一个输入索引
+func countInputs(args []string) int {
+	count := 0
+	for _, arg := range args {
+		if arg == "-i" {
+			count++
+		}
+	}
+	return count
+}
+
+// appendVideoEffects 在各任务最终视频流上追加通用视频效果（调色、预设画面重构、水印、文字叠加、字幕烧录等）
+// sourcePath: 本地源视频路径（预设运动跟随的分析遍使用），没有单一视频源的任务为空
+// graph: 已有的filter_complex（可为空）；label: 当前视频流标签，无滤镜时为输入流（如"0:v"）
+// nextInput: 下一个可用的输入索引，效果所需的额外输入（如水印图片）从该索引开始
+// 返回值：额外输入参数、新的filter_complex、最终视频流标签、错误
+func (s *FFmpegService) appendVideoEffects(params model.TaskInputParams, sourcePath string, graph string, label string, nextInput int, tempFiles *[]string) ([]string, string, string, error) {
+	var inputArgs []string
+	var filters []string
+
+	if graph != "" {
+		filters = append(filters, graph)
+	}
+
+	// 调色（放在叠加类效果之前，水印和文字保持原色）
+	if params.Color != nil {
+		filter, err := s.buildColorFilter(params.Color, label, "graded", tempFiles)
+		if err != nil {
+			return nil, "", "", err
+		}
+		filters = append(filters, filter)
+		label = "graded"
+	}
+
+	// 按输出预设重构画面（后续效果以预设尺寸定位）
+	if presetNeedsReframe(params) {
+		filter, err := s.buildPresetReframe(params, sourcePath, label, "preset")
+		if err != nil {
+			return nil, "", "", err
+		}
+		filters = append(filters, filter)
+		label = "preset"
+	}
+
+	// 水印叠加
+	if params.Watermark != nil {
+		localPath, err := s.downloadInput(params.Watermark.ImagePath, tempFiles)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("download watermark image failed: %w", err)
+		}
+		inputArgs = append(inputArgs, "-i", localPath)
+
+		filters = append(filters, s.buildWatermarkFilter(params.Watermark, label, nextInput, "wm"))
+		label = "wm"
+		nextInput++
+	}
+
+	// 文字叠加
+	if len(params.TextOverlays) > 0 {
+		filter, err := s.buildTextOverlays(params.TextOverlays, params.Variables, label, "txt", params.Height, tempFiles)
+		if err != nil {
+			return nil, "", "", err
+		}
+		filters = append(filters, filter)
+		label = "txt"
+	}
+
+	// 字幕烧录（放在水印和文字之后，保证字幕在最上层）
+	if params.Subtitle != nil && subtitleMode(params.Subtitle) == "burn" {
+		localPath, err := s.downloadInput(params.Subtitle.Path, tempFiles)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("download subtitle failed: %w", err)
+		}
+
+		filters = append(filters, s.buildSubtitleFilter(params.Subtitle, localPath, label, "sub"))
+		label = "sub"
+	}
+
+	return inputArgs, strings.Join(filters, ";"), label, nil
+}
+
+// buildWatermarkFilter 构建水印叠加滤镜
+// 水印先转为rgba并调整透明度，再用scale2ref按视频宽度缩放，最后overlay到锚点位置
+func (s *FFmpegService) buildWatermarkFilter(wm *model.WatermarkOptions, videoLabel string, inputIndex int, outLabel string) string {
+	var filters []string
+
+	logo := fmt.Sprintf("[%d:v]format=rgba", inputIndex)
+	if wm.Opacity > 0 && wm.Opacity < 1 {
+		logo += fmt.Sprintf(",colorchannelmixer=aa=%.2f", wm.Opacity)
+	}
+	filters = append(filters, logo+"[wmlogo]")
+
+	base, logoLabel := videoLabel, "wmlogo"
+	if wm.Scale > 0 {
+		// scale2ref中main_w为视频宽度，dar为水印自身宽高比
+		filters = append(filters, fmt.Sprintf("[wmlogo][%s]scale2ref=w=main_w*%.4f:h=ow/dar[wmscaled][wmbase]", videoLabel, wm.Scale))
+		base, logoLabel = "wmbase", "wmscaled"
+	}
+
+	x, y := s.overlayPosition(wm.Position, wm.Margin)
+	overlay := fmt.Sprintf("[%s][%s]overlay=x=%s:y=%s", base, logoLabel, x, y)
+	if enable := s.enableExpr(wm.StartTime, wm.EndTime); enable != "" {
+		overlay += ":enable=" + enable
+	}
+	filters = append(filters, overlay+fmt.Sprintf("[%s]", outLabel))
+
+	return strings.Join(filters, ";")
+}
+
+// overlayPosition 根据锚点位置和边距计算overlay的x/y表达式，top/bottom水平居中
+func (s *FFmpegService) overlayPosition(position string, margin int) (string, string) {
+	switch position {
+	case "top":
+		return "(W-w)/2", fmt.Sprintf("%d", margin)
+	case "bottom":
+		return "(W-w)/2", fmt.Sprintf("H-h-%d", margin)
+	case "top_left":
+		return fmt.Sprintf("%d", margin), fmt.Sprintf("%d", margin)
+	case "top_right":
+		return fmt.Sprintf("W-w-%d", margin), fmt.Sprintf("%d", margin)
+	case "bottom_left":
+		return fmt.Sprintf("%d", margin), fmt.Sprintf("H-h-%d", margin)
+	case "center":
+		return "(W-w)/2", "(H-h)/2"
+	default:
+		// 默认右下角
+		return fmt.Sprintf("W-w-%d", margin), fmt.Sprintf("H-h-%d", margin)
+	}
+}
+
+// enableExpr 构建滤镜的时间窗口表达式，未设置时间窗口时返回空字符串
+func (s *FFmpegService) enableExpr(start, end float64) string {
+	switch {
+	case end > 0:
+		return fmt.Sprintf("'between(t,%.3f,%.3f)'", start, end)
+	case start > 0:
+		return fmt.Sprintf("'gte(t,%.3f)'", start)
+	default:
+		return ""
+	}
+}
+
+// validateWatermark 验证水印参数
+func (s *FFmpegService) validateWatermark(wm *model.WatermarkOptions) error {
+	if wm.ImagePath == "" {
+		return fmt.Errorf("watermark image_path is required")
+	}
+	if err := s.parser.ValidateFile(wm.ImagePath); err != nil {
+		return fmt.Errorf("invalid watermark image: %w", err)
+	}
+
+	switch wm.Position {
+	case "", "top", "center", "bottom", "top_left", "top_right", "bottom_left", "bottom_right":
+	default:
+		return fmt.Errorf("invalid watermark position: %s", wm.Position)
+	}
+
+	if wm.Scale < 0 || wm.Scale > 1 {
+		return fmt.Errorf("watermark scale must be between 0 and 1")
+	}
+	if wm.Opacity < 0 || wm.Opacity > 1 {
+		return fmt.Errorf("watermark opacity must be between 0 and 1")
+	}
+	if wm.Margin < 0 {
+		return fmt.Errorf("watermark margin must not be negative")
+	}
+	if wm.StartTime < 0 || (wm.EndTime > 0 && wm.EndTime <= wm.StartTime) {
+		return fmt.Errorf("invalid watermark time window: %.2f-%.2f", wm.StartTime, wm.EndTime)
+	}
+	return nil
+}
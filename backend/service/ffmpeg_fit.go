@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// fitMode 获取适配模式，默认stretch（拉伸到目标尺寸）
+func fitMode(fit *model.FitOptions) string {
+	if fit == nil || fit.Mode == "" {
+		return "stretch"
+	}
+	return fit.Mode
+}
+
+// buildFitFilter 构建将画面适配到width x height的滤镜图片段：[in] -> [out]
+// contain：按比例缩小后居中，空白处填充纯色或模糊的原图；cover：按比例放大后按焦点裁剪；stretch：直接拉伸
+func (s *FFmpegService) buildFitFilter(fit *model.FitOptions, in, out string, width, height int) string {
+	switch fitMode(fit) {
+	case "contain":
+		contain := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", width, height)
+		if fit.Background == "blur" {
+			// 背景为铺满画面并模糊的原图副本，前景叠加在中央
+			bg, fg := out+"bg", out+"fg"
+			return fmt.Sprintf("[%s]split=2[%s][%s];[%s]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,boxblur=20:2[%sblur];[%s]%s[%sfit];[%sblur][%sfit]overlay=(W-w)/2:(H-h)/2,setsar=1[%s]",
+				in, bg, fg, bg, width, height, width, height, out, fg, contain, out, out, out, out)
+		}
+
+		color := "black"
+		if fit.Background != "" {
+			color = "0x" + strings.TrimPrefix(fit.Background, "#")
+		}
+		return fmt.Sprintf("[%s]%s,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:color=%s,setsar=1[%s]", in, contain, width, height, color, out)
+	case "cover":
+		// 焦点（相对位置）尽量位于裁剪区域中央，并限制裁剪区域不超出画面
+		focalX, focalY := fit.FocalX, fit.FocalY
+		if focalX == 0 {
+			focalX = 0.5
+		}
+		if focalY == 0 {
+			focalY = 0.5
+		}
+		return fmt.Sprintf("[%s]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d:x='min(max(iw*%.3f-ow/2,0),iw-ow)':y='min(max(ih*%.3f-oh/2,0),ih-oh)',setsar=1[%s]",
+			in, width, height, width, height, focalX, focalY, out)
+	default: // stretch
+		return fmt.Sprintf("[%s]scale=%d:%d,setsar=1[%s]", in, width, height, out)
+	}
+}
+
+// validateFit 验证画面适配参数
+func (s *FFmpegService) validateFit(fit *model.FitOptions) error {
+	switch fit.Mode {
+	case "", "stretch", "cover":
+	case "contain":
+		if fit.Background != "" && fit.Background != "blur" && !hexColorPattern.MatchString(fit.Background) {
+			return fmt.Errorf("invalid fit background: %s (expected #RRGGBB, RRGGBB or blur)", fit.Background)
+		}
+	default:
+		return fmt.Errorf("invalid fit mode: %s", fit.Mode)
+	}
+	if fit.FocalX < 0 || fit.FocalX > 1 || fit.FocalY < 0 || fit.FocalY > 1 {
+		return fmt.Errorf("fit focal_x and focal_y must be between 0 and 1")
+	}
+	return nil
+}
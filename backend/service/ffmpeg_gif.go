@@ -0,0 +1,221 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 动图默认参数：限制宽度和帧率以控制文件大小
+const (
+	defaultAnimatedWidth = 480
+	defaultAnimatedFPS   = 12
+)
+
+// BuildGIFCommand 构建动图（GIF/动态WebP）的ffmpeg命令
+// GIF使用palettegen/paletteuse两级管线：先统计整段视频生成最优调色板，再按调色板和抖动算法量化每帧
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildGIFCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	var opts model.GIFOptions
+	if params.GIF != nil {
+		opts = *params.GIF
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+
+	// 计算动图时长
+	duration := info.Duration - opts.StartTime
+	if opts.Duration > 0 && opts.Duration < duration {
+		duration = opts.Duration
+	}
+	if duration <= 0 {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("start_time %.2f is beyond video duration %.2f", opts.StartTime, info.Duration)
+	}
+
+	fps := params.FPS
+	if fps == 0 {
+		fps = defaultAnimatedFPS
+	}
+	width := params.Width
+	if width == 0 {
+		width = defaultAnimatedWidth
+	}
+	totalFrames := int(duration * float64(fps))
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+	}
+	if opts.StartTime > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", opts.StartTime))
+	}
+	if opts.Duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", opts.Duration))
+	}
+	args = append(args, "-i", localInputPath)
+
+	// 画质修复（如反交错）需要在降帧之前应用于源视频
+	graph, videoLabel := "", "0:v:0"
+	if params.Restore != nil {
+		restore, err := s.buildRestoreChain(params.Restore, localInputPath)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if restore != "" {
+			graph, videoLabel = fmt.Sprintf("[0:v:0]%s[restored]", restore), "restored"
+		}
+	}
+
+	// 通用视频效果（水印、字幕等）在降帧缩放之前应用
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, localInputPath, graph, videoLabel, 1, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 限制宽度：不放大小于目标宽度的视频
+	prepare := fmt.Sprintf("[%s]fps=%d,scale='min(%d,iw)':-2:flags=lanczos", videoLabel, fps, width)
+
+	format := s.getAnimatedFormat(params.OutputFormat)
+	var filters []string
+	if graph != "" {
+		filters = append(filters, graph)
+	}
+	if format == "webp" {
+		filters = append(filters, prepare+"[anim]")
+	} else {
+		filters = append(filters,
+			prepare+",split[pal][frames]",
+			fmt.Sprintf("[pal]%s[palette]", s.buildPaletteGen(opts)),
+			fmt.Sprintf("[frames][palette]%s[anim]", s.buildPaletteUse(opts)),
+		)
+	}
+
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[anim]",
+		"-an",
+	)
+
+	if format == "webp" {
+		quality := opts.Quality
+		if quality == 0 {
+			quality = 75
+		}
+		loop := opts.Loop
+		if loop < 0 {
+			loop = 1 // WebP不支持-1，播放一次即循环1次
+		}
+		args = append(args,
+			"-c:v", "libwebp",
+			"-lossless", "0",
+			"-q:v", fmt.Sprintf("%d", quality),
+			"-loop", fmt.Sprintf("%d", loop),
+		)
+	} else {
+		args = append(args, "-loop", fmt.Sprintf("%d", opts.Loop))
+	}
+
+	args = append(args,
+		"-f", format,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// buildPaletteGen 构建调色板生成滤镜
+func (s *FFmpegService) buildPaletteGen(opts model.GIFOptions) string {
+	filter := "palettegen"
+	var options []string
+	if opts.MaxColors > 0 {
+		options = append(options, fmt.Sprintf("max_colors=%d", opts.MaxColors))
+	}
+	if opts.StatsMode != "" {
+		options = append(options, "stats_mode="+opts.StatsMode)
+	}
+	if len(options) > 0 {
+		filter += "=" + strings.Join(options, ":")
+	}
+	return filter
+}
+
+// buildPaletteUse 构建调色板应用滤镜
+func (s *FFmpegService) buildPaletteUse(opts model.GIFOptions) string {
+	dither := opts.Dither
+	if dither == "" {
+		dither = "sierra2_4a"
+	}
+
+	filter := "paletteuse=dither=" + dither
+	if dither == "bayer" && opts.BayerScale > 0 {
+		filter += fmt.Sprintf(":bayer_scale=%d", opts.BayerScale)
+	}
+	switch opts.StatsMode {
+	case "diff":
+		// 与diff统计模式配合，只重绘变化区域
+		filter += ":diff_mode=rectangle"
+	case "single":
+		// 每帧使用各自的调色板，而不是只用第一帧的调色板
+		filter += ":new=1"
+	}
+	return filter
+}
+
+// getAnimatedFormat 获取动图输出格式，默认GIF
+func (s *FFmpegService) getAnimatedFormat(format string) string {
+	if format == "webp" {
+		return "webp"
+	}
+	return "gif"
+}
+
+// validateGIF 验证动图参数
+func (s *FFmpegService) validateGIF(opts *model.GIFOptions) error {
+	switch opts.Dither {
+	case "", "bayer", "floyd_steinberg", "sierra2", "sierra2_4a", "heckbert", "none":
+	default:
+		return fmt.Errorf("invalid gif dither: %s", opts.Dither)
+	}
+	switch opts.StatsMode {
+	case "", "full", "diff", "single":
+	default:
+		return fmt.Errorf("invalid gif stats_mode: %s", opts.StatsMode)
+	}
+	if opts.BayerScale < 0 || opts.BayerScale > 5 {
+		return fmt.Errorf("gif bayer_scale must be between 0 and 5")
+	}
+	if opts.MaxColors != 0 && (opts.MaxColors < 2 || opts.MaxColors > 256) {
+		return fmt.Errorf("gif max_colors must be between 2 and 256")
+	}
+	if opts.Quality < 0 || opts.Quality > 100 {
+		return fmt.Errorf("webp quality must be between 0 and 100")
+	}
+	if opts.StartTime < 0 || opts.Duration < 0 {
+		return fmt.Errorf("gif start_time and duration must not be negative")
+	}
+	if opts.Loop < -1 {
+		return fmt.Errorf("gif loop must be -1, 0 or a positive count")
+	}
+	return nil
+}
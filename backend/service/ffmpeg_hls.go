@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// HLSMasterPlaylist HLS主播放列表文件名
+const HLSMasterPlaylist = "master.m3u8"
+
+// buildHLSArgs 构建HLS多码率打包参数
+// 一次ffmpeg运行中将视频split为多路并分别缩放编码，通过-var_stream_map生成主播放列表和各档子播放列表
+func (s *FFmpegService) buildHLSArgs(params model.TaskInputParams, inputPath string, info *ffmpeg.MediaInfo, outputPath string, tempFiles *[]string) ([]string, error) {
+	var opts model.HLSOptions
+	if params.HLS != nil {
+		opts = *params.HLS
+	}
+	renditions := s.resolveRenditions(opts.Renditions, info.Height)
+	outputDir := filepath.Dir(outputPath)
+
+	// 预先创建各档子目录
+	for _, r := range renditions {
+		if err := os.MkdirAll(filepath.Join(outputDir, "stream_"+r.Name), 0755); err != nil {
+			return nil, fmt.Errorf("create hls output dir failed: %w", err)
+		}
+	}
+
+	segmentDuration := opts.SegmentDuration
+	if segmentDuration == 0 {
+		segmentDuration = 6
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", inputPath,
+	}
+
+	effectInputs, filterComplex, err := s.buildLadderFilter(params, inputPath, renditions, tempFiles)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, effectInputs...)
+	args = append(args, "-filter_complex", filterComplex)
+
+	// 每档各自映射一路音频，var_stream_map按档位分组
+	hasAudio := info.AudioCodec != ""
+	var streamMap []string
+	for i, r := range renditions {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i))
+		entry := fmt.Sprintf("v:%d", i)
+		if hasAudio {
+			args = append(args, "-map", "0:a:0")
+			entry += fmt.Sprintf(",a:%d", i)
+		}
+		streamMap = append(streamMap, entry+",name:"+r.Name)
+	}
+
+	args = append(args, s.ladderVideoArgs(params, renditions, segmentDuration)...)
+	if hasAudio {
+		args = append(args, "-c:a", s.getAudioCodec(params.AudioCodec))
+		for i, r := range renditions {
+			args = append(args, fmt.Sprintf("-b:a:%d", i), r.AudioBitrate)
+		}
+	}
+
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_filename", filepath.Join(outputDir, "stream_%v", "seg_%05d.ts"),
+		"-master_pl_name", filepath.Base(outputPath),
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-y",
+		filepath.Join(outputDir, "stream_%v", "playlist.m3u8"),
+	)
+
+	return args, nil
+}
+
+// validateHLS 验证HLS参数
+func (s *FFmpegService) validateHLS(opts *model.HLSOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.SegmentDuration < 0 {
+		return fmt.Errorf("hls segment_duration must not be negative")
+	}
+	return s.validateRenditions(opts.Renditions)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// 响度标准化默认目标（常见播客/流媒体规范）
+const (
+	defaultLoudnessI   = -16.0
+	defaultLoudnessTP  = -1.5
+	defaultLoudnessLRA = 11.0
+)
+
+// loudnessTasks 支持响度标准化的任务类型（HLS/DASH输出除外）
+var loudnessTasks = map[string]bool{
+	"transcode":            true,
+	"watermark":            true,
+	"image_audio_to_video": true,
+	"image_slideshow":      true,
+	"audio_convert":        true,
+}
+
+// loudnessTargets 获取响度目标值，未指定的使用默认值
+func (s *FFmpegService) loudnessTargets(opts *model.LoudnessOptions) (float64, float64, float64) {
+	i, tp, lra := opts.TargetI, opts.TruePeak, opts.LRA
+	if i == 0 {
+		i = defaultLoudnessI
+	}
+	if tp == 0 {
+		tp = defaultLoudnessTP
+	}
+	if lra == 0 {
+		lra = defaultLoudnessLRA
+	}
+	return i, tp, lra
+}
+
+// buildLoudnormFilter 对inputPath的第一条音频流执行loudnorm测量遍，并用实测值构建第二遍的线性标准化滤镜
+func (s *FFmpegService) buildLoudnormFilter(opts *model.LoudnessOptions, inputPath string, sampleRate int) (string, error) {
+	return s.measureLoudnorm(opts, sampleRate, func(filter string) (*ffmpeg.LoudnormStats, error) {
+		return s.parser.MeasureLoudness(inputPath, filter)
+	})
+}
+
+// measureLoudnorm 用measure执行测量遍（传入带print_format=json的loudnorm滤镜），并用实测值构建第二遍的线性标准化滤镜
+// 测量的信号需要与第二遍loudnorm的输入一致；第二遍同样以print_format=json输出统计，任务完成后从日志中解析结果
+// 实测值无效（如静音输入）时回退为单遍动态标准化
+func (s *FFmpegService) measureLoudnorm(opts *model.LoudnessOptions, sampleRate int, measure func(filter string) (*ffmpeg.LoudnormStats, error)) (string, error) {
+	i, tp, lra := s.loudnessTargets(opts)
+	target := fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f", i, tp, lra)
+
+	filter := target + ":print_format=json"
+	stats, err := measure(filter)
+	switch {
+	case errors.Is(err, ffmpeg.ErrInvalidLoudnormStats):
+	case err != nil:
+		return "", err
+	default:
+		filter = fmt.Sprintf("%s:measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f:linear=true:print_format=json",
+			target, stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset)
+	}
+
+	// loudnorm内部以192kHz处理，输出前恢复采样率
+	if sampleRate == 0 {
+		sampleRate = 48000
+	}
+	return fmt.Sprintf("%s,aresample=%d", filter, sampleRate), nil
+}
+
+// LoudnessReport 从标准化遍的ffmpeg日志中解析响度报告
+// 标准化遍的input_*与测量遍的实测值一致，output_*为处理后的结果
+func (s *FFmpegService) LoudnessReport(opts *model.LoudnessOptions, stderrLog string) (*model.LoudnessReport, error) {
+	stats, err := ffmpeg.ParseLoudnormStats(stderrLog)
+	if err != nil {
+		return nil, err
+	}
+
+	i, tp, lra := s.loudnessTargets(opts)
+	return &model.LoudnessReport{
+		TargetI:           i,
+		TargetTP:          tp,
+		TargetLRA:         lra,
+		MeasuredI:         stats.InputI,
+		MeasuredTP:        stats.InputTP,
+		MeasuredLRA:       stats.InputLRA,
+		MeasuredThresh:    stats.InputThresh,
+		OutputI:           stats.OutputI,
+		OutputTP:          stats.OutputTP,
+		OutputLRA:         stats.OutputLRA,
+		NormalizationType: stats.NormalizationType,
+	}, nil
+}
+
+// validateLoudness 验证响度标准化参数（取值范围与loudnorm滤镜一致）
+func (s *FFmpegService) validateLoudness(opts *model.LoudnessOptions) error {
+	if opts.TargetI != 0 && (opts.TargetI < -70 || opts.TargetI > -5) {
+		return fmt.Errorf("loudness target_i must be between -70 and -5 LUFS")
+	}
+	if opts.TruePeak != 0 && (opts.TruePeak < -9 || opts.TruePeak > 0) {
+		return fmt.Errorf("loudness true_peak must be between -9 and 0 dBTP")
+	}
+	if opts.LRA != 0 && (opts.LRA < 1 || opts.LRA > 50) {
+		return fmt.Errorf("loudness lra must be between 1 and 50 LU")
+	}
+	return nil
+}
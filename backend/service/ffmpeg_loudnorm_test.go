@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+func TestMeasureLoudnorm(t *testing.T) {
+	s := &FFmpegService{}
+	opts := &model.LoudnessOptions{}
+
+	tests := []struct {
+		name    string
+		stats   *ffmpeg.LoudnormStats
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "two pass",
+			stats: &ffmpeg.LoudnormStats{InputI: -23.54, InputTP: -7.96, InputLRA: 6.2, InputThresh: -34.01, TargetOffset: 0.02},
+			want:  "loudnorm=I=-16.0:TP=-1.5:LRA=11.0:measured_I=-23.54:measured_TP=-7.96:measured_LRA=6.20:measured_thresh=-34.01:offset=0.02:linear=true:print_format=json,aresample=48000",
+		},
+		{
+			// 静音输入的实测值无效，回退为单遍
+			name: "single pass fallback",
+			err:  fmt.Errorf("%w: input_i is \"-inf\"", ffmpeg.ErrInvalidLoudnormStats),
+			want: "loudnorm=I=-16.0:TP=-1.5:LRA=11.0:print_format=json,aresample=48000",
+		},
+		{
+			name:    "measurement failed",
+			err:     fmt.Errorf("loudnorm measurement failed: exit status 1"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.measureLoudnorm(opts, 0, func(string) (*ffmpeg.LoudnormStats, error) {
+				return tt.stats, tt.err
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("measureLoudnorm() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("measureLoudnorm() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("measureLoudnorm() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
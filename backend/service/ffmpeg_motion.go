@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// motionTypes 支持的运动效果（random在构建命令时随机选择其中一种）
+var motionTypes = []string{"zoom_in", "zoom_out", "pan_left", "pan_right", "pan_up", "pan_down"}
+
+// defaultMotionZoom 默认最大缩放倍数
+const defaultMotionZoom = 1.2
+
+// slideMotions 获取每张图片的运动效果：优先使用image_motions中的对应项，否则使用全局motion
+// random在此处解析为具体效果，保证记录的命令可以原样回放
+func (s *FFmpegService) slideMotions(params model.TaskInputParams, numImages int) []string {
+	motions := make([]string, numImages)
+	for i := range motions {
+		motion := params.Motion
+		if i < len(params.ImageMotions) && params.ImageMotions[i] != "" {
+			motion = params.ImageMotions[i]
+		}
+		motions[i] = s.resolveMotion(motion)
+	}
+	return motions
+}
+
+// resolveMotion 将random解析为随机的具体运动效果
+func (s *FFmpegService) resolveMotion(motion string) string {
+	if motion == "random" {
+		return motionTypes[rand.Intn(len(motionTypes))]
+	}
+	return motion
+}
+
+// buildSlideInput 构建单张图片的预处理滤镜链：[i:v] -> [vi]
+// 先按适配模式缩放到画面尺寸；有运动效果时使用zoompan逐帧计算缩放和位移，帧数由图片时长和帧率决定
+// caption为可选的drawtext滤镜
+func (s *FFmpegService) buildSlideInput(index, width, height, fps int, duration float64, motion string, zoom float64, fit *model.FitOptions, caption string) string {
+	if caption != "" {
+		caption = "," + caption
+	}
+
+	in := fmt.Sprintf("%d:v", index)
+	fitted := fmt.Sprintf("f%d", index)
+
+	if motion == "" || motion == "none" {
+		return fmt.Sprintf("%s;[%s]fps=%d%s,settb=AVTB[v%d];", s.buildFitFilter(fit, in, fitted, width, height), fitted, fps, caption, index)
+	}
+
+	if zoom <= 1 {
+		zoom = defaultMotionZoom
+	}
+	frames := int(duration*float64(fps) + 0.5)
+	z, x, y := s.zoompanExpr(motion, zoom, frames)
+
+	// 先适配到输出尺寸的2倍，减少zoompan按整数像素取景产生的抖动
+	// 输入已按输出帧率循环，d=1表示每个输入帧输出一帧，on为当前帧序号
+	return fmt.Sprintf("%s;[%s]zoompan=z='%s':x='%s':y='%s':d=1:s=%dx%d:fps=%d%s,settb=AVTB[v%d];",
+		s.buildFitFilter(fit, in, fitted, width*2, height*2), fitted, z, x, y, width, height, fps, caption, index)
+}
+
+// zoompanExpr 构建zoompan的缩放和取景位置表达式
+func (s *FFmpegService) zoompanExpr(motion string, zoom float64, frames int) (string, string, string) {
+	progress := fmt.Sprintf("on/%d", frames)
+	centerX := "iw/2-(iw/zoom/2)"
+	centerY := "ih/2-(ih/zoom/2)"
+	fixedZoom := fmt.Sprintf("%.3f", zoom)
+
+	switch motion {
+	case "zoom_out":
+		return fmt.Sprintf("%.3f-%.3f*%s", zoom, zoom-1, progress), centerX, centerY
+	case "pan_left":
+		return fixedZoom, fmt.Sprintf("(iw-iw/zoom)*(1-%s)", progress), centerY
+	case "pan_right":
+		return fixedZoom, fmt.Sprintf("(iw-iw/zoom)*%s", progress), centerY
+	case "pan_up":
+		return fixedZoom, centerX, fmt.Sprintf("(ih-ih/zoom)*(1-%s)", progress)
+	case "pan_down":
+		return fixedZoom, centerX, fmt.Sprintf("(ih-ih/zoom)*%s", progress)
+	default: // zoom_in
+		return fmt.Sprintf("1+%.3f*%s", zoom-1, progress), centerX, centerY
+	}
+}
+
+// validateMotion 验证运动效果名称
+func (s *FFmpegService) validateMotion(motion string) error {
+	switch motion {
+	case "", "none", "random":
+		return nil
+	}
+	for _, m := range motionTypes {
+		if motion == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid motion: %s", motion)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// socialPreset 社交平台输出预设
+type socialPreset struct {
+	Width        int
+	Height       int
+	FPS          int
+	VideoBitrate string
+	MaxRate      string // 码率上限，平台转码对突发码率敏感
+	BufSize      string // 码率控制缓冲区
+	AudioBitrate string
+	MaxDuration  float64 // 平台允许的最长时长（秒），0表示不限制
+}
+
+// socialPresets 支持的预设，输出统一为H.264/AAC的mp4
+var socialPresets = map[string]socialPreset{
+	"tiktok_9x16":      {Width: 1080, Height: 1920, FPS: 30, VideoBitrate: "6M", MaxRate: "8M", BufSize: "16M", AudioBitrate: "128k", MaxDuration: 600},
+	"instagram_square": {Width: 1080, Height: 1080, FPS: 30, VideoBitrate: "5M", MaxRate: "6M", BufSize: "12M", AudioBitrate: "128k", MaxDuration: 60},
+	"instagram_4x5":    {Width: 1080, Height: 1350, FPS: 30, VideoBitrate: "5M", MaxRate: "6M", BufSize: "12M", AudioBitrate: "128k", MaxDuration: 60},
+	"youtube_1080p":    {Width: 1920, Height: 1080, FPS: 30, VideoBitrate: "8M", MaxRate: "12M", BufSize: "24M", AudioBitrate: "192k"},
+}
+
+// 运动跟随裁剪的分析参数
+const (
+	presetTrackSampleFPS = 2.0 // 每秒采样帧数
+	presetTrackSmoothing = 3   // 滑动平均的半窗口（采样点数）
+	presetTrackMaxPoints = 120 // 焦点表达式的最大关键点数量
+)
+
+// presetCanvasTasks 直接渲染画布的任务类型：按预设尺寸和对应的适配方式渲染，不需要再重构画面
+var presetCanvasTasks = map[string]bool{
+	"image_audio_to_video": true,
+	"image_slideshow":      true,
+	"compose":              true,
+	"chromakey":            true,
+}
+
+// presetTrackTasks 支持跟随运动裁剪的任务类型（输出时间轴和画面与源视频一致）
+var presetTrackTasks = map[string]bool{
+	"transcode": true,
+	"watermark": true,
+}
+
+// presetReframe 获取预设的画面重构方式，默认居中裁剪
+func presetReframe(opts *model.PresetOptions) string {
+	if opts.Reframe == "" {
+		return "crop"
+	}
+	return opts.Reframe
+}
+
+// ApplyPreset 将预设展开到任务参数中：未显式设置的码率、帧率、编码使用预设值，输出固定为mp4
+// 画布类任务直接使用预设尺寸和对应的适配方式渲染；其他视频任务在通用视频效果中重构画面
+// 任务类型是否支持预设由ValidateTaskOptions检查
+func (s *FFmpegService) ApplyPreset(taskType string, params model.TaskInputParams) model.TaskInputParams {
+	if params.Preset == nil {
+		return params
+	}
+
+	mode := presetReframe(params.Preset)
+	preset := socialPresets[params.Preset.Name]
+	params.OutputFormat = "mp4"
+	if params.VideoCodec == "" {
+		params.VideoCodec = "libx264"
+	}
+	if params.AudioCodec == "" {
+		params.AudioCodec = "aac"
+	}
+	if params.VideoBitrate == "" {
+		params.VideoBitrate = preset.VideoBitrate
+	}
+	if params.AudioBitrate == "" {
+		params.AudioBitrate = preset.AudioBitrate
+	}
+	if params.FPS == 0 {
+		params.FPS = preset.FPS
+	}
+
+	if presetCanvasTasks[taskType] {
+		params.Width, params.Height = preset.Width, preset.Height
+		if params.Fit == nil {
+			// 静态画面没有运动可跟随，track按居中裁剪处理
+			params.Fit = &model.FitOptions{Mode: "cover"}
+			if mode == "blur" {
+				params.Fit = &model.FitOptions{Mode: "contain", Background: "blur"}
+			}
+		}
+	}
+	return params
+}
+
+// presetNeedsReframe 判断是否需要在通用视频效果中按预设重构画面（画布类任务已按预设尺寸渲染）
+func presetNeedsReframe(params model.TaskInputParams) bool {
+	return params.Preset != nil && params.Width == 0 && params.Height == 0
+}
+
+// buildPresetReframe 构建按预设重构画面的滤镜：[in] -> [out]
+// crop：居中裁剪；blur：完整显示并以模糊的原图填充；track：对源视频做运动分析，裁剪框随运动区域平移
+func (s *FFmpegService) buildPresetReframe(params model.TaskInputParams, sourcePath, in, out string) (string, error) {
+	preset := socialPresets[params.Preset.Name]
+
+	switch presetReframe(params.Preset) {
+	case "blur":
+		return s.buildFitFilter(&model.FitOptions{Mode: "contain", Background: "blur"}, in, out, preset.Width, preset.Height), nil
+	case "track":
+		if sourcePath == "" {
+			return "", fmt.Errorf("preset reframe track requires a source video")
+		}
+		samples, err := s.parser.DetectMotion(sourcePath, presetTrackSampleFPS)
+		if err != nil {
+			return "", err
+		}
+		focalX := presetTrackExpr(samples, func(m ffmpeg.MotionSample) float64 { return m.CenterX })
+		focalY := presetTrackExpr(samples, func(m ffmpeg.MotionSample) float64 { return m.CenterY })
+		return fmt.Sprintf("[%s]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d:x='clip((%s)*iw-ow/2,0,iw-ow)':y='clip((%s)*ih-oh/2,0,ih-oh)',setsar=1[%s]",
+			in, preset.Width, preset.Height, preset.Width, preset.Height, focalX, focalY, out), nil
+	default: // crop
+		return s.buildFitFilter(&model.FitOptions{Mode: "cover"}, in, out, preset.Width, preset.Height), nil
+	}
+}
+
+// presetTrackExpr 将运动采样平滑后构建为随时间t变化的焦点表达式（分段线性插值），没有运动时居中
+func presetTrackExpr(samples []ffmpeg.MotionSample, value func(ffmpeg.MotionSample) float64) string {
+	if len(samples) == 0 {
+		return "0.5"
+	}
+
+	// 滑动平均，避免裁剪框随画面细节抖动
+	smoothed := make([]float64, len(samples))
+	for i := range samples {
+		lo, hi := max(0, i-presetTrackSmoothing), min(len(samples)-1, i+presetTrackSmoothing)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			sum += value(samples[j])
+		}
+		smoothed[i] = sum / float64(hi-lo+1)
+	}
+
+	// 控制表达式长度：长视频按间隔抽取关键点，并保留最后一个采样点
+	step := (len(samples) + presetTrackMaxPoints - 1) / presetTrackMaxPoints
+	var times, values []float64
+	for i := 0; i < len(samples); i += step {
+		times = append(times, samples[i].Time)
+		values = append(values, smoothed[i])
+	}
+	if last := len(samples) - 1; times[len(times)-1] != samples[last].Time {
+		times = append(times, samples[last].Time)
+		values = append(values, smoothed[last])
+	}
+	if len(times) == 1 {
+		return fmt.Sprintf("%.4f", values[0])
+	}
+
+	// 各时间段互斥，求和即为当前时刻的插值
+	terms := []string{fmt.Sprintf("lt(t,%.3f)*%.4f", times[0], values[0])}
+	for i := 0; i < len(times)-1; i++ {
+		slope := (values[i+1] - values[i]) / (times[i+1] - times[i])
+		terms = append(terms, fmt.Sprintf("gte(t,%.3f)*lt(t,%.3f)*(%.4f%+.4f*(t-%.3f))",
+			times[i], times[i+1], values[i], slope, times[i]))
+	}
+	terms = append(terms, fmt.Sprintf("gte(t,%.3f)*%.4f", times[len(times)-1], values[len(values)-1]))
+	return strings.Join(terms, "+")
+}
+
+// AppendPresetLimits 在主输出文件前追加预设的码率上限和最长时长，返回参数和按最长时长截断后的总帧数
+// 标题卡之后会拼接到输出首尾，主视频的最长时长需要扣除标题卡的时长
+func (s *FFmpegService) AppendPresetLimits(params model.TaskInputParams, args []string, totalFrames int) ([]string, int) {
+	if params.Preset == nil {
+		return args, totalFrames
+	}
+	maxDuration := socialPresets[params.Preset.Name].MaxDuration
+	if maxDuration > 0 {
+		maxDuration -= titleCardsDuration(params.TitleCards)
+	}
+	return appendPresetLimitArgs(params, args, maxDuration), presetLimitFrames(params, totalFrames, maxDuration)
+}
+
+// AppendTitleCardPresetLimits 标题卡拼接遍会重新编码整个输出，同样追加预设的码率上限和最长时长
+func (s *FFmpegService) AppendTitleCardPresetLimits(params model.TaskInputParams, args []string, totalFrames int) ([]string, int) {
+	if params.Preset == nil {
+		return args, totalFrames
+	}
+	maxDuration := socialPresets[params.Preset.Name].MaxDuration
+	return appendPresetLimitArgs(params, args, maxDuration), presetLimitFrames(params, totalFrames, maxDuration)
+}
+
+// appendPresetLimitArgs 在输出路径之前插入-maxrate/-bufsize，maxDuration大于0时追加-t
+func appendPresetLimitArgs(params model.TaskInputParams, args []string, maxDuration float64) []string {
+	if len(args) == 0 {
+		return args
+	}
+	preset := socialPresets[params.Preset.Name]
+
+	limits := []string{"-maxrate", preset.MaxRate, "-bufsize", preset.BufSize}
+	if maxDuration > 0 {
+		limits = append(limits, "-t", fmt.Sprintf("%.3f", maxDuration))
+	}
+
+	// 输出选项需要位于输出路径（最后一个参数）之前
+	output := args[len(args)-1]
+	result := append(args[:len(args)-1:len(args)-1], limits...)
+	return append(result, output)
+}
+
+// presetLimitFrames 按最长时长截断总帧数（输出帧率为预设展开后的fps），保证进度能够到达100%
+func presetLimitFrames(params model.TaskInputParams, totalFrames int, maxDuration float64) int {
+	fps := params.FPS
+	if fps == 0 {
+		fps = socialPresets[params.Preset.Name].FPS
+	}
+	if maxDuration <= 0 || fps == 0 {
+		return totalFrames
+	}
+	if limit := int(maxDuration * float64(fps)); totalFrames > limit {
+		return limit
+	}
+	return totalFrames
+}
+
+// validatePreset 验证输出预设参数
+func (s *FFmpegService) validatePreset(params model.TaskInputParams) error {
+	opts := params.Preset
+	if _, ok := socialPresets[opts.Name]; !ok {
+		names := make([]string, 0, len(socialPresets))
+		for name := range socialPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("invalid preset: %s (supported: %s)", opts.Name, strings.Join(names, ", "))
+	}
+
+	switch opts.Reframe {
+	case "", "crop", "blur", "track":
+	default:
+		return fmt.Errorf("invalid preset reframe: %s (expected crop, blur or track)", opts.Reframe)
+	}
+
+	if params.Width > 0 || params.Height > 0 {
+		return fmt.Errorf("preset cannot be combined with width/height")
+	}
+	if params.OutputFormat != "" && params.OutputFormat != "mp4" {
+		return fmt.Errorf("preset output is mp4, output_format %s is not supported", params.OutputFormat)
+	}
+	if maxDuration := socialPresets[opts.Name].MaxDuration; maxDuration > 0 && titleCardsDuration(params.TitleCards) >= maxDuration {
+		return fmt.Errorf("title cards exceed the %.0f second limit of preset %s", maxDuration, opts.Name)
+	}
+	return nil
+}
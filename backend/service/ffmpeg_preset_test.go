@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+func TestPresetTrackExpr(t *testing.T) {
+	centerX := func(m ffmpeg.MotionSample) float64 { return m.CenterX }
+
+	tests := []struct {
+		name    string
+		samples []ffmpeg.MotionSample
+		want    string
+	}{
+		{
+			name: "no motion",
+			want: "0.5",
+		},
+		{
+			name:    "single sample",
+			samples: []ffmpeg.MotionSample{{Time: 3, CenterX: 0.25}},
+			want:    "0.2500",
+		},
+		{
+			// 两个采样点都在滑动平均窗口内，平滑后为同一个值
+			name:    "smoothed",
+			samples: []ffmpeg.MotionSample{{Time: 0, CenterX: 0.2}, {Time: 1, CenterX: 0.4}},
+			want:    "lt(t,0.000)*0.3000+gte(t,0.000)*lt(t,1.000)*(0.3000+0.0000*(t-0.000))+gte(t,1.000)*0.3000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := presetTrackExpr(tt.samples, centerX); got != tt.want {
+				t.Errorf("presetTrackExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresetTrackExprLimitsPoints(t *testing.T) {
+	// 10分钟视频按2fps采样
+	samples := make([]ffmpeg.MotionSample, 1200)
+	for i := range samples {
+		samples[i] = ffmpeg.MotionSample{Time: float64(i) / presetTrackSampleFPS, CenterX: float64(i%10) / 10}
+	}
+
+	expr := presetTrackExpr(samples, func(m ffmpeg.MotionSample) float64 { return m.CenterX })
+
+	// 每个关键点对应一个gte(t,...)项
+	if points := strings.Count(expr, "gte(t,"); points > presetTrackMaxPoints+1 {
+		t.Errorf("expression has %d points, want at most %d", points, presetTrackMaxPoints+1)
+	}
+	// 最后一个采样点保留，之后的时间保持最后的焦点
+	last := fmt.Sprintf("gte(t,%.3f)*", samples[len(samples)-1].Time)
+	if !strings.Contains(expr, last) {
+		t.Errorf("expression does not end at the last sample %s", last)
+	}
+}
+
+func TestAppendPresetLimitsCapsFrames(t *testing.T) {
+	s := &FFmpegService{}
+	args := []string{"-i", "in.mp4", "out.mp4"}
+
+	tests := []struct {
+		name       string
+		params     model.TaskInputParams
+		frames     int
+		wantFrames int
+		wantT      string
+	}{
+		{
+			name:       "longer than limit",
+			params:     model.TaskInputParams{Preset: &model.PresetOptions{Name: "instagram_square"}, FPS: 30},
+			frames:     3000,
+			wantFrames: 1800,
+			wantT:      "60.000",
+		},
+		{
+			name:       "shorter than limit",
+			params:     model.TaskInputParams{Preset: &model.PresetOptions{Name: "instagram_square"}, FPS: 30},
+			frames:     900,
+			wantFrames: 900,
+			wantT:      "60.000",
+		},
+		{
+			name: "title cards shorten main video",
+			params: model.TaskInputParams{
+				Preset:     &model.PresetOptions{Name: "instagram_square"},
+				FPS:        30,
+				TitleCards: []model.TitleCard{{Duration: 5}},
+			},
+			frames:     3000,
+			wantFrames: 1650,
+			wantT:      "55.000",
+		},
+		{
+			name:       "no max duration",
+			params:     model.TaskInputParams{Preset: &model.PresetOptions{Name: "youtube_1080p"}, FPS: 30},
+			frames:     100000,
+			wantFrames: 100000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, frames := s.AppendPresetLimits(tt.params, append([]string(nil), args...), tt.frames)
+			if frames != tt.wantFrames {
+				t.Errorf("AppendPresetLimits() frames = %d, want %d", frames, tt.wantFrames)
+			}
+			if got[len(got)-1] != "out.mp4" {
+				t.Errorf("AppendPresetLimits() last arg = %s, want out.mp4", got[len(got)-1])
+			}
+			var duration string
+			for i, arg := range got {
+				if arg == "-t" {
+					duration = got[i+1]
+				}
+			}
+			if duration != tt.wantT {
+				t.Errorf("AppendPresetLimits() -t = %q, want %q", duration, tt.wantT)
+			}
+		})
+	}
+}
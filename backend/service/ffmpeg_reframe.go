@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 自动裁剪的分析参数：跳过开头10%（片头常有黑场），最多分析20秒
+const (
+	cropDetectDefaultLimit = 24
+	cropDetectSampleDur    = 20.0
+)
+
+// BuildReframeCommand 构建裁剪/旋转/翻转的ffmpeg命令
+// ffmpeg解码时按源视频的旋转元数据自动校正方向，之后依次应用裁剪、旋转、翻转和缩放，
+// 并清除输出的rotate标签，避免播放器再次旋转
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildReframeCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	opts := params.Reframe
+	if opts == nil {
+		return nil, 0, nil, fmt.Errorf("no reframe options provided")
+	}
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+
+	fps := float64(params.FPS)
+	if fps == 0 {
+		fps = info.FPS
+	}
+	if fps == 0 {
+		fps = 25
+	}
+	totalFrames := int(info.Duration * fps)
+
+	// 裁剪区域以校正旋转后的显示画面为准
+	displayWidth, displayHeight := info.DisplaySize()
+	crop, err := s.reframeCropArea(opts, localInputPath, info.Duration, displayWidth, displayHeight)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+
+	// 画质修复在裁剪等几何处理之前应用
+	var filters []string
+	if params.Restore != nil {
+		restore, err := s.buildRestoreChain(params.Restore, localInputPath)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if restore != "" {
+			filters = append(filters, restore)
+		}
+	}
+	if crop != nil {
+		filters = append(filters, fmt.Sprintf("crop=%d:%d:%d:%d", crop.Width, crop.Height, crop.X, crop.Y))
+	}
+	switch opts.Rotate {
+	case 90:
+		filters = append(filters, "transpose=clock")
+	case 180:
+		filters = append(filters, "hflip", "vflip")
+	case 270:
+		filters = append(filters, "transpose=cclock")
+	}
+	if opts.FlipH {
+		filters = append(filters, "hflip")
+	}
+	if opts.FlipV {
+		filters = append(filters, "vflip")
+	}
+	if scale := s.buildScaleFilter(params.Width, params.Height); scale != "" {
+		filters = append(filters, scale)
+	}
+	if len(filters) == 0 {
+		// 自动裁剪未检测到黑边且没有其他操作（以及画质修复未检测到隔行）
+		filters = append(filters, "null")
+	}
+	graph := fmt.Sprintf("[0:v:0]%s,setsar=1[reframed]", strings.Join(filters, ","))
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", localInputPath,
+	}
+
+	// 通用视频效果（水印等），额外输入从索引1开始
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, localInputPath, graph, "reframed", 1, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", graph,
+		"-map", "["+videoLabel+"]",
+		"-map", "0:a?",
+	)
+
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("reframe requires re-encoding, video_codec cannot be copy")
+	}
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+		"-metadata:s:v:0", "rotate=0", // 画面已按元数据校正
+	)
+	if params.FPS > 0 {
+		args = append(args, "-r", fmt.Sprintf("%d", params.FPS))
+	}
+
+	audioCodec := s.getAudioCodec(params.AudioCodec)
+	args = append(args, "-c:a", audioCodec)
+	if audioCodec != "copy" {
+		args = append(args, "-b:a", s.getAudioBitrate(params.AudioBitrate))
+	}
+	args = append(args, subtitleArgs...)
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	args = append(args,
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// reframeCropArea 计算裁剪区域：手动裁剪检查是否超出画面，自动裁剪执行cropdetect分析遍
+// 返回nil表示不需要裁剪；宽高取偶数以满足yuv420p
+func (s *FFmpegService) reframeCropArea(opts *model.ReframeOptions, inputPath string, duration float64, width, height int) (*model.CropRect, error) {
+	switch {
+	case opts.Crop != nil:
+		crop := *opts.Crop
+		if crop.X+crop.Width > width || crop.Y+crop.Height > height {
+			return nil, fmt.Errorf("crop %dx%d+%d+%d exceeds the %dx%d frame", crop.Width, crop.Height, crop.X, crop.Y, width, height)
+		}
+		crop.Width, crop.Height = crop.Width&^1, crop.Height&^1
+		return &crop, nil
+	case opts.AutoCrop:
+		limit := opts.CropLimit
+		if limit == 0 {
+			limit = cropDetectDefaultLimit
+		}
+		start := duration * 0.1
+		sampleDur := math.Min(duration-start, cropDetectSampleDur)
+		if sampleDur <= 0 {
+			start, sampleDur = 0, cropDetectSampleDur
+		}
+
+		area, err := s.parser.DetectCrop(inputPath, start, sampleDur, limit)
+		if err != nil {
+			return nil, err
+		}
+		if area.Width >= width && area.Height >= height {
+			return nil, nil // 没有黑边
+		}
+		return &model.CropRect{X: area.X, Y: area.Y, Width: area.Width &^ 1, Height: area.Height &^ 1}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// validateReframe 验证裁剪/旋转/翻转参数
+func (s *FFmpegService) validateReframe(opts *model.ReframeOptions) error {
+	if opts.Crop != nil {
+		if opts.AutoCrop {
+			return fmt.Errorf("reframe crop and auto_crop cannot be used together")
+		}
+		if opts.Crop.X < 0 || opts.Crop.Y < 0 || opts.Crop.Width < 2 || opts.Crop.Height < 2 {
+			return fmt.Errorf("invalid reframe crop: %dx%d+%d+%d", opts.Crop.Width, opts.Crop.Height, opts.Crop.X, opts.Crop.Y)
+		}
+	}
+	if opts.CropLimit < 0 || opts.CropLimit > 255 {
+		return fmt.Errorf("reframe crop_limit must be between 0 and 255")
+	}
+
+	switch opts.Rotate {
+	case 0, 90, 180, 270:
+	default:
+		return fmt.Errorf("invalid reframe rotate: %d (expected 0, 90, 180 or 270)", opts.Rotate)
+	}
+
+	if opts.Crop == nil && !opts.AutoCrop && opts.Rotate == 0 && !opts.FlipH && !opts.FlipV {
+		return fmt.Errorf("reframe requires at least one of crop, auto_crop, rotate, flip_h or flip_v")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 画质修复参数
+const (
+	restoreIdetFrames      = 500 // idet分析的帧数
+	restoreDefaultStrength = 0.5
+)
+
+// restoreTasks 支持画质修复的任务类型（单一视频源，修复作用于源视频）
+var restoreTasks = map[string]bool{
+	"transcode": true,
+	"watermark": true,
+	"clip":      true,
+	"retime":    true,
+	"reframe":   true,
+	"gif":       true,
+	"chromakey": true,
+}
+
+// buildRestoreChain 构建画质修复滤镜链（不带标签，逗号连接），顺序固定为：
+// 反交错 -> 降噪 -> 去色带 -> 锐化（先去除隔行和噪点，避免后续步骤放大瑕疵）
+// auto反交错会先对inputPath执行idet分析，逐行视频不加反交错；没有需要应用的滤镜时返回空字符串
+func (s *FFmpegService) buildRestoreChain(opts *model.RestoreOptions, inputPath string) (string, error) {
+	var filters []string
+
+	switch opts.Deinterlace {
+	case "auto":
+		stats, err := s.parser.DetectInterlace(inputPath, restoreIdetFrames)
+		if err != nil {
+			return "", err
+		}
+		if stats.Interlaced() {
+			filters = append(filters, fmt.Sprintf("bwdif=mode=send_frame:parity=%s:deint=all", stats.Parity()))
+		}
+	case "yadif", "bwdif":
+		filters = append(filters, opts.Deinterlace+"=mode=send_frame:parity=auto:deint=all")
+	}
+
+	strength := opts.DenoiseStrength
+	if strength == 0 {
+		strength = restoreDefaultStrength
+	}
+	switch opts.Denoise {
+	case "hqdn3d":
+		// 强度0.5时约等于hqdn3d默认值4:3:6:4.5
+		filters = append(filters, fmt.Sprintf("hqdn3d=%.2f:%.2f:%.2f:%.2f", 8*strength, 6*strength, 12*strength, 9*strength))
+	case "nlmeans":
+		filters = append(filters, fmt.Sprintf("nlmeans=s=%.2f", 1+10*strength))
+	}
+
+	if opts.Deband {
+		filters = append(filters, "deband")
+	}
+
+	if opts.Sharpen > 0 {
+		// 只锐化亮度，避免色彩噪点
+		filters = append(filters, fmt.Sprintf("unsharp=5:5:%.2f:5:5:0", opts.Sharpen))
+	}
+
+	return strings.Join(filters, ","), nil
+}
+
+// validateRestore 验证画质修复参数
+func (s *FFmpegService) validateRestore(opts *model.RestoreOptions) error {
+	switch opts.Deinterlace {
+	case "", "auto", "yadif", "bwdif":
+	default:
+		return fmt.Errorf("invalid restore deinterlace: %s (expected auto, yadif or bwdif)", opts.Deinterlace)
+	}
+	switch opts.Denoise {
+	case "", "hqdn3d", "nlmeans":
+	default:
+		return fmt.Errorf("invalid restore denoise: %s (expected hqdn3d or nlmeans)", opts.Denoise)
+	}
+	if opts.DenoiseStrength < 0 || opts.DenoiseStrength > 1 {
+		return fmt.Errorf("restore denoise_strength must be between 0 and 1")
+	}
+	if opts.Sharpen < 0 || opts.Sharpen > 2 {
+		return fmt.Errorf("restore sharpen must be between 0 and 2")
+	}
+	if opts.Deinterlace == "" && opts.Denoise == "" && !opts.Deband && opts.Sharpen == 0 {
+		return fmt.Errorf("restore requires at least one of deinterlace, denoise, deband or sharpen")
+	}
+	return nil
+}
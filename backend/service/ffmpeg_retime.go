@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 变速倍数范围，以及倒放允许的最大时长（reverse/areverse需要把整段缓存在内存中）
+const (
+	retimeMinSpeed      = 0.25
+	retimeMaxSpeed      = 4.0
+	retimeMaxReverseDur = 60.0
+)
+
+// retimeSpeed 获取变速倍数，默认1（原速）
+func retimeSpeed(opts *model.RetimeOptions) float64 {
+	if opts.Speed == 0 {
+		return 1
+	}
+	return opts.Speed
+}
+
+// BuildRetimeCommand 构建变速/倒放的ffmpeg命令
+// 视频用setpts调整时间戳（慢放可用minterpolate补帧），音频用atempo链保持音高，倒放使用reverse/areverse
+// 返回值：命令参数、总帧数（按变速后的时长计算）、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildRetimeCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	opts := params.Retime
+	if opts == nil {
+		return nil, 0, nil, fmt.Errorf("no retime options provided")
+	}
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	info, err := s.parser.GetMediaInfo(localInputPath)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+	if opts.Reverse && info.Duration > retimeMaxReverseDur {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("reverse is limited to clips of %.0f seconds, input is %.1f seconds (clip it first)", retimeMaxReverseDur, info.Duration)
+	}
+
+	fps := float64(params.FPS)
+	if fps == 0 {
+		fps = info.FPS
+	}
+	if fps == 0 {
+		fps = 25
+	}
+
+	// 输出时长 = 源时长 / 倍数
+	speed := retimeSpeed(opts)
+	outputDuration := info.Duration / speed
+	totalFrames := int(outputDuration * fps)
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", localInputPath,
+	}
+
+	// 视频：画质修复 -> 倒放 -> 变速 -> 补帧/统一帧率 -> 缩放
+	var videoFilters []string
+	if params.Restore != nil {
+		restore, err := s.buildRestoreChain(params.Restore, localInputPath)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+		if restore != "" {
+			videoFilters = append(videoFilters, restore)
+		}
+	}
+	if opts.Reverse {
+		videoFilters = append(videoFilters, "reverse")
+	}
+	if speed != 1 {
+		videoFilters = append(videoFilters, fmt.Sprintf("setpts=PTS/%.4f", speed))
+	}
+	if opts.Interpolate && speed < 1 {
+		// 运动补偿插帧，慢放时生成中间帧而不是重复帧
+		videoFilters = append(videoFilters, fmt.Sprintf("minterpolate=fps=%.3f:mi_mode=mci:mc_mode=aobmc:me_mode=bidir:vsbmc=1", fps))
+	} else {
+		videoFilters = append(videoFilters, fmt.Sprintf("fps=%.3f", fps))
+	}
+	if scale := s.buildScaleFilter(params.Width, params.Height); scale != "" {
+		videoFilters = append(videoFilters, scale)
+	}
+	graph := fmt.Sprintf("[0:v:0]%s[retimed]", strings.Join(videoFilters, ","))
+
+	// 通用视频效果（水印等），额外输入从索引1开始
+	effectInputs, graph, videoLabel, err := s.appendVideoEffects(params, localInputPath, graph, "retimed", 1, &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, effectInputs...)
+
+	// 音频：倒放 -> atempo链（源视频没有音频时忽略）
+	hasAudio := info.AudioCodec != ""
+	if hasAudio {
+		var audioFilters []string
+		if opts.Reverse {
+			audioFilters = append(audioFilters, "areverse")
+		}
+		audioFilters = append(audioFilters, atempoChain(speed)...)
+		if len(audioFilters) == 0 {
+			audioFilters = append(audioFilters, "anull")
+		}
+		graph += fmt.Sprintf(";[0:a:0]%s[aout]", strings.Join(audioFilters, ","))
+	}
+
+	// 软字幕轨道
+	subtitleInputs, subtitleArgs, err := s.buildSubtitleTrack(params, countInputs(args), &tempFiles)
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	args = append(args, subtitleInputs...)
+
+	args = append(args,
+		"-filter_complex", graph,
+		"-map", "["+videoLabel+"]",
+	)
+	if hasAudio {
+		args = append(args, "-map", "[aout]")
+	}
+
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, fmt.Errorf("retime requires re-encoding, video_codec cannot be copy")
+	}
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+	)
+	if hasAudio {
+		audioCodec := s.getAudioCodec(params.AudioCodec)
+		if audioCodec == "copy" {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, fmt.Errorf("retime requires re-encoding, audio_codec cannot be copy")
+		}
+		args = append(args,
+			"-c:a", audioCodec,
+			"-b:a", s.getAudioBitrate(params.AudioBitrate),
+		)
+	}
+	args = append(args, subtitleArgs...)
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	args = append(args,
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, totalFrames, tempFiles, nil
+}
+
+// atempoChain 将变速倍数拆分为atempo滤镜链
+// 单个atempo只接受0.5-2.0，超出范围时串联多个（如4x = 2.0*2.0，0.25x = 0.5*0.5）
+func atempoChain(speed float64) []string {
+	var chain []string
+	for speed > 2.0 {
+		chain = append(chain, "atempo=2.0")
+		speed /= 2.0
+	}
+	for speed < 0.5 {
+		chain = append(chain, "atempo=0.5")
+		speed /= 0.5
+	}
+	if speed != 1 {
+		chain = append(chain, fmt.Sprintf("atempo=%.4f", speed))
+	}
+	return chain
+}
+
+// validateRetime 验证变速参数
+func (s *FFmpegService) validateRetime(opts *model.RetimeOptions) error {
+	speed := retimeSpeed(opts)
+	if speed < retimeMinSpeed || speed > retimeMaxSpeed {
+		return fmt.Errorf("retime speed must be between %.2f and %.0f", retimeMinSpeed, retimeMaxSpeed)
+	}
+	if speed == 1 && !opts.Reverse {
+		return fmt.Errorf("retime requires a speed other than 1 or reverse")
+	}
+	if opts.Interpolate && speed >= 1 {
+		return fmt.Errorf("retime interpolate only applies to slow motion (speed < 1)")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAtempoChain(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  []string
+	}{
+		{1, nil},
+		{1.5, []string{"atempo=1.5000"}},
+		{2, []string{"atempo=2.0000"}},
+		{0.5, []string{"atempo=0.5000"}},
+		{3, []string{"atempo=2.0", "atempo=1.5000"}},
+		{4, []string{"atempo=2.0", "atempo=2.0000"}},
+		{0.25, []string{"atempo=0.5", "atempo=0.5000"}},
+		{0.3, []string{"atempo=0.5", "atempo=0.6000"}},
+	}
+
+	for _, tt := range tests {
+		if got := atempoChain(tt.speed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("atempoChain(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// defaultImageDuration 每张图片默认显示时长（秒）
+const defaultImageDuration = 3.0
+
+// slideshowSlide 解析后的单张幻灯片
+type slideshowSlide struct {
+	imagePath  string
+	duration   float64
+	caption    string
+	style      *model.TextStyle
+	transition string // 到下一张的转场，最后一张为空
+	motion     string
+}
+
+// resolveSlides 解析幻灯片列表：优先使用slides，否则由image_paths及全局参数展开
+// 每项未设置的时长、转场、运动效果依次回退到逐项数组参数和全局参数
+func (s *FFmpegService) resolveSlides(params model.TaskInputParams) []slideshowSlide {
+	items := params.Slides
+	if len(items) == 0 {
+		items = make([]model.Slide, len(params.ImagePaths))
+		for i, path := range params.ImagePaths {
+			items[i] = model.Slide{ImagePath: path}
+		}
+	}
+
+	imageDuration := params.ImageDuration
+	if imageDuration == 0 {
+		imageDuration = defaultImageDuration
+	}
+
+	motions := s.slideMotions(params, len(items))
+	transitions := s.slideTransitions(params, len(items))
+
+	slides := make([]slideshowSlide, len(items))
+	for i, item := range items {
+		slide := slideshowSlide{
+			imagePath: item.ImagePath,
+			duration:  item.Duration,
+			caption:   expandTemplate(item.Caption, params.Variables),
+			style:     item.CaptionStyle,
+			motion:    motions[i],
+		}
+		if slide.duration == 0 {
+			slide.duration = imageDuration
+		}
+		if slide.style == nil {
+			slide.style = params.CaptionStyle
+		}
+		if item.Motion != "" {
+			slide.motion = s.resolveMotion(item.Motion)
+		}
+		if i < len(transitions) {
+			slide.transition = transitions[i]
+			if item.Transition != "" {
+				slide.transition = s.resolveTransition(item.Transition)
+			}
+		}
+		slides[i] = slide
+	}
+	return slides
+}
+
+// slideshowDuration 计算幻灯片总时长：有转场的衔接处前后两张重叠transitionDur
+func (s *FFmpegService) slideshowDuration(slides []slideshowSlide, transitionDur float64) float64 {
+	var total float64
+	for _, slide := range slides {
+		total += slide.duration
+		if slide.transition != "" && slide.transition != "none" {
+			total -= transitionDur
+		}
+	}
+	return total
+}
+
+// validateSlideTransitions 转场时长必须短于衔接处前后两张图片的时长
+func (s *FFmpegService) validateSlideTransitions(slides []slideshowSlide, transitionDur float64) error {
+	for i, slide := range slides {
+		if slide.transition == "" || slide.transition == "none" {
+			continue
+		}
+		if transitionDur >= slide.duration || transitionDur >= slides[i+1].duration {
+			return fmt.Errorf("transition_dur %.2f must be shorter than the durations of slides %d and %d", transitionDur, i, i+1)
+		}
+	}
+	return nil
+}
+
+// validateSlides 验证幻灯片列表
+func (s *FFmpegService) validateSlides(slides []model.Slide) error {
+	for i, slide := range slides {
+		if slide.ImagePath == "" {
+			return fmt.Errorf("slides[%d]: image_path is required", i)
+		}
+		if err := s.parser.ValidateFile(slide.ImagePath); err != nil {
+			return fmt.Errorf("invalid image file at slides[%d]: %w", i, err)
+		}
+		if slide.Duration < 0 {
+			return fmt.Errorf("slides[%d]: duration must not be negative", i)
+		}
+		if err := s.validateTransition(slide.Transition); err != nil {
+			return fmt.Errorf("slides[%d]: %w", i, err)
+		}
+		if err := s.validateMotion(slide.Motion); err != nil {
+			return fmt.Errorf("slides[%d]: %w", i, err)
+		}
+		if slide.CaptionStyle != nil {
+			if err := s.validateTextStyle(slide.CaptionStyle); err != nil {
+				return fmt.Errorf("slides[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+	"github.com/fangzio/ffmpeg-platform/pkg/ffmpeg"
+)
+
+// slideshowAudioLabel 幻灯片音轨滤镜的输出标签
+const slideshowAudioLabel = "aout"
+
+// 旁白闪避默认参数
+const (
+	defaultDuckingRatio = 8.0
+	duckingThreshold    = 0.05 // 旁白电平超过该值时开始压低背景音乐
+)
+
+// soundtrackFormat 统一各音轨的采样率和声道，便于侧链压缩和混音
+const soundtrackFormat = "aformat=sample_rates=48000:channel_layouts=stereo"
+
+// buildSlideshowAudio 构建幻灯片音轨滤镜，输出为[aout]
+// 背景音乐：音量 -> 裁剪到视频时长（循环输入时必需）-> 淡入淡出；旁白：补齐静音到视频时长
+// 两者同时存在时以旁白作为侧链压低背景音乐（sidechaincompress）再混音（amix）
+// musicInput/voiceoverInput为输入索引，-1表示没有；loudnorm为可选的响度标准化滤镜
+func (s *FFmpegService) buildSlideshowAudio(params model.TaskInputParams, musicInput, voiceoverInput int, duration float64, loudnorm string) string {
+	var filters []string
+
+	if musicInput >= 0 {
+		chain := []string{soundtrackFormat}
+		if params.BackgroundVolume > 0 && params.BackgroundVolume != 1 {
+			chain = append(chain, fmt.Sprintf("volume=%.2f", params.BackgroundVolume))
+		}
+		chain = append(chain, fmt.Sprintf("atrim=0:%.3f", duration), "asetpts=PTS-STARTPTS")
+		if params.AudioFadeIn > 0 {
+			chain = append(chain, fmt.Sprintf("afade=t=in:st=0:d=%.2f", params.AudioFadeIn))
+		}
+		if params.AudioFadeOut > 0 {
+			start := duration - params.AudioFadeOut
+			if start < 0 {
+				start = 0
+			}
+			chain = append(chain, fmt.Sprintf("afade=t=out:st=%.3f:d=%.2f", start, params.AudioFadeOut))
+		}
+		filters = append(filters, fmt.Sprintf("[%d:a]%s[music]", musicInput, strings.Join(chain, ",")))
+	}
+
+	mixed := "music"
+	if voiceoverInput >= 0 {
+		voice := fmt.Sprintf("[%d:a]%s,apad,atrim=0:%.3f,asetpts=PTS-STARTPTS", voiceoverInput, soundtrackFormat, duration)
+		if musicInput < 0 {
+			filters = append(filters, voice+"[voice]")
+			mixed = "voice"
+		} else {
+			ratio := params.DuckingRatio
+			if ratio == 0 {
+				ratio = defaultDuckingRatio
+			}
+			filters = append(filters,
+				voice+",asplit=2[voice][voicekey]",
+				fmt.Sprintf("[music][voicekey]sidechaincompress=threshold=%.2f:ratio=%.1f:attack=20:release=400[ducked]", duckingThreshold, ratio),
+				"[ducked][voice]amix=inputs=2:duration=first:dropout_transition=0:normalize=0[mixed]",
+			)
+			mixed = "mixed"
+		}
+	}
+
+	final := "anull"
+	if loudnorm != "" {
+		final = loudnorm
+	}
+	filters = append(filters, fmt.Sprintf("[%s]%s[%s]", mixed, final, slideshowAudioLabel))
+
+	return strings.Join(filters, ";")
+}
+
+// buildSlideshowLoudnorm 构建幻灯片音轨的响度标准化滤镜
+// 测量遍只输入音频，使用与输出相同的音轨滤镜（音量、循环裁剪、淡入淡出、旁白闪避和混音），测量的是最终混音
+func (s *FFmpegService) buildSlideshowLoudnorm(params model.TaskInputParams, musicPath, voiceoverPath string, duration float64) (string, error) {
+	var inputArgs []string
+	musicInput, voiceoverInput := -1, -1
+	if musicPath != "" {
+		musicInput = countInputs(inputArgs)
+		if params.BackgroundAudioLoop {
+			inputArgs = append(inputArgs, "-stream_loop", "-1")
+		}
+		inputArgs = append(inputArgs, "-i", musicPath)
+	}
+	if voiceoverPath != "" {
+		voiceoverInput = countInputs(inputArgs)
+		inputArgs = append(inputArgs, "-i", voiceoverPath)
+	}
+
+	return s.measureLoudnorm(params.Loudness, params.SampleRate, func(filter string) (*ffmpeg.LoudnormStats, error) {
+		graph := s.buildSlideshowAudio(params, musicInput, voiceoverInput, duration, filter)
+		return s.parser.MeasureLoudnessGraph(inputArgs, graph, slideshowAudioLabel)
+	})
+}
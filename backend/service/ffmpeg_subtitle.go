@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// hexColorPattern #RRGGBB格式颜色，#可省略
+var hexColorPattern = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// subtitleMode 获取字幕模式，默认烧录
+func subtitleMode(sub *model.SubtitleOptions) string {
+	if sub.Mode == "" {
+		return "burn"
+	}
+	return sub.Mode
+}
+
+// buildSubtitleFilter 构建字幕烧录滤镜
+// 未设置样式的ASS字幕使用ass滤镜保留原始样式，其余使用subtitles滤镜并通过force_style覆盖样式
+func (s *FFmpegService) buildSubtitleFilter(sub *model.SubtitleOptions, localPath string, videoLabel string, outLabel string) string {
+	forceStyle := s.subtitleForceStyle(sub)
+	ext := strings.ToLower(filepath.Ext(localPath))
+
+	if ext == ".ass" && forceStyle == "" {
+		return fmt.Sprintf("[%s]ass=filename='%s'[%s]", videoLabel, escapeFilterPath(localPath), outLabel)
+	}
+
+	filter := fmt.Sprintf("[%s]subtitles=filename='%s'", videoLabel, escapeFilterPath(localPath))
+	if forceStyle != "" {
+		filter += fmt.Sprintf(":force_style='%s'", forceStyle)
+	}
+	return filter + fmt.Sprintf("[%s]", outLabel)
+}
+
+// subtitleForceStyle 将样式参数转换为ASS样式覆盖字符串
+func (s *FFmpegService) subtitleForceStyle(sub *model.SubtitleOptions) string {
+	var styles []string
+	if sub.FontName != "" {
+		styles = append(styles, "FontName="+sub.FontName)
+	}
+	if sub.FontSize > 0 {
+		styles = append(styles, fmt.Sprintf("FontSize=%d", sub.FontSize))
+	}
+	if sub.PrimaryColor != "" {
+		styles = append(styles, "PrimaryColour="+assColor(sub.PrimaryColor))
+	}
+	if sub.OutlineColor != "" {
+		styles = append(styles, "OutlineColour="+assColor(sub.OutlineColor))
+	}
+	if sub.Outline > 0 {
+		styles = append(styles, fmt.Sprintf("Outline=%d", sub.Outline))
+	}
+	if sub.MarginV > 0 {
+		styles = append(styles, fmt.Sprintf("MarginV=%d", sub.MarginV))
+	}
+	return strings.Join(styles, ",")
+}
+
+// buildSubtitleTrack 构建软字幕轨道参数，未启用软字幕时返回空
+// inputIndex: 字幕文件的输入索引
+// 返回值：字幕输入参数、输出参数（映射、编码、语言）、错误
+func (s *FFmpegService) buildSubtitleTrack(params model.TaskInputParams, inputIndex int, tempFiles *[]string) ([]string, []string, error) {
+	if params.Subtitle == nil || subtitleMode(params.Subtitle) != "soft" {
+		return nil, nil, nil
+	}
+
+	codec, err := s.subtitleCodec(params.OutputFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localPath, err := s.downloadInput(params.Subtitle.Path, tempFiles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("download subtitle failed: %w", err)
+	}
+
+	inputArgs := []string{"-i", localPath}
+	outputArgs := []string{
+		"-map", fmt.Sprintf("%d:s", inputIndex),
+		"-c:s", codec,
+	}
+	if params.Subtitle.Language != "" {
+		outputArgs = append(outputArgs, "-metadata:s:s:0", "language="+params.Subtitle.Language)
+	}
+	return inputArgs, outputArgs, nil
+}
+
+// subtitleCodec 根据输出容器选择软字幕编码
+func (s *FFmpegService) subtitleCodec(outputFormat string) (string, error) {
+	switch s.getOutputFormat(outputFormat) {
+	case "mp4", "mov":
+		return "mov_text", nil
+	case "matroska":
+		return "copy", nil // MKV原生支持SRT/ASS/WebVTT
+	case "webm":
+		return "webvtt", nil
+	default:
+		return "", fmt.Errorf("output format %s does not support soft subtitles", outputFormat)
+	}
+}
+
+// validateSubtitle 验证字幕参数
+func (s *FFmpegService) validateSubtitle(sub *model.SubtitleOptions, outputFormat string) error {
+	if sub.Path == "" {
+		return fmt.Errorf("subtitle path is required")
+	}
+
+	switch strings.ToLower(filepath.Ext(strings.SplitN(sub.Path, "?", 2)[0])) {
+	case ".srt", ".ass", ".ssa", ".vtt":
+	default:
+		return fmt.Errorf("unsupported subtitle format: %s (supported: srt, ass, vtt)", sub.Path)
+	}
+
+	switch subtitleMode(sub) {
+	case "burn":
+	case "soft":
+		if _, err := s.subtitleCodec(outputFormat); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid subtitle mode: %s", sub.Mode)
+	}
+
+	for _, color := range []string{sub.PrimaryColor, sub.OutlineColor} {
+		if color != "" && !hexColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid subtitle color: %s (expected #RRGGBB or RRGGBB)", color)
+		}
+	}
+
+	if err := s.parser.ValidateFile(sub.Path); err != nil {
+		return fmt.Errorf("invalid subtitle file: %w", err)
+	}
+	return nil
+}
+
+// assColor 将#RRGGBB转换为ASS颜色格式&HAABBGGRR
+func assColor(color string) string {
+	hex := strings.TrimPrefix(color, "#")
+	return fmt.Sprintf("&H00%s%s%s", hex[4:6], hex[2:4], hex[0:2])
+}
+
+// escapeFilterPath 转义滤镜参数中的文件路径（调用方用单引号包裹）
+// 先做滤镜参数层级的转义；单引号内的反斜杠是字面量，引号需要先闭合、转义后再重新打开
+func escapeFilterPath(path string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `:`, `\:`, `'`, `\'`).Replace(path)
+	return strings.ReplaceAll(escaped, `'`, `'\''`)
+}
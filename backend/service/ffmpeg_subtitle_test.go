@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestEscapeFilterPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/tmp/sub.srt", "/tmp/sub.srt"},
+		{`C:\subs\a.srt`, `C\:\\subs\\a.srt`},
+		// 单引号：滤镜参数层级转义为\'，再闭合引号、转义、重新打开
+		{"/tmp/it's.ass", `/tmp/it\'\''s.ass`},
+		{"Noto Sans", "Noto Sans"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFilterPath(tt.path); got != tt.want {
+			t.Errorf("escapeFilterPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// defaultTextMargin 文字距画面边缘的默认像素距离
+const defaultTextMargin = 40
+
+// buildDrawText 构建drawtext滤镜（不含输入输出标签）
+// 文字写入临时文件通过textfile引用，避免对文字内容做滤镜转义；height为画面高度，用于计算默认字号
+// extra为附加的drawtext选项（如enable、alpha）
+func (s *FFmpegService) buildDrawText(text string, style *model.TextStyle, height int, tempFiles *[]string, extra ...string) (string, error) {
+	var st model.TextStyle
+	if style != nil {
+		st = *style
+	}
+
+	textFile, err := s.writeTempFile("text_*.txt", text)
+	if err != nil {
+		return "", err
+	}
+	*tempFiles = append(*tempFiles, textFile)
+
+	fontSize := st.FontSize
+	if fontSize == 0 {
+		fontSize = height / 18
+	}
+	fontColor := st.FontColor
+	if fontColor == "" {
+		fontColor = "#FFFFFF"
+	}
+	margin := st.Margin
+	if margin == 0 {
+		margin = defaultTextMargin
+	}
+
+	options := []string{
+		fmt.Sprintf("textfile='%s'", escapeFilterPath(textFile)),
+		"expansion=none",
+		fmt.Sprintf("fontsize=%d", fontSize),
+		"fontcolor=" + drawTextColor(fontColor, 1),
+	}
+	if st.FontName != "" {
+		options = append(options, fmt.Sprintf("font='%s'", escapeFilterPath(st.FontName)))
+	}
+
+	x, y := textPosition(st.Position, margin)
+	options = append(options, "x="+x, "y="+y)
+
+	if st.BoxColor != "" {
+		opacity := st.BoxOpacity
+		if opacity == 0 {
+			opacity = 0.5
+		}
+		options = append(options,
+			"box=1",
+			"boxcolor="+drawTextColor(st.BoxColor, opacity),
+			fmt.Sprintf("boxborderw=%d", fontSize/3),
+		)
+	} else {
+		// 无背景框时加阴影保证可读性
+		options = append(options, "shadowcolor=black@0.6", "shadowx=2", "shadowy=2")
+	}
+	options = append(options, extra...)
+
+	return "drawtext=" + strings.Join(options, ":"), nil
+}
+
+// textPosition 计算文字位置表达式：top/center/bottom水平居中，四角用于角标和字幕条（lower third）
+func textPosition(position string, margin int) (string, string) {
+	centerX := "(w-text_w)/2"
+	left := fmt.Sprintf("%d", margin)
+	right := fmt.Sprintf("w-text_w-%d", margin)
+	top := fmt.Sprintf("%d", margin)
+	bottom := fmt.Sprintf("h-text_h-%d", margin)
+
+	switch position {
+	case "top":
+		return centerX, top
+	case "center":
+		return centerX, "(h-text_h)/2"
+	case "top_left":
+		return left, top
+	case "top_right":
+		return right, top
+	case "bottom_left":
+		return left, bottom
+	case "bottom_right":
+		return right, bottom
+	default: // bottom
+		return centerX, bottom
+	}
+}
+
+// drawTextColor 将#RRGGBB转换为drawtext颜色格式0xRRGGBB@透明度
+func drawTextColor(color string, opacity float64) string {
+	return fmt.Sprintf("0x%s@%.2f", strings.TrimPrefix(color, "#"), opacity)
+}
+
+// validateTextStyle 验证文字样式
+func (s *FFmpegService) validateTextStyle(style *model.TextStyle) error {
+	switch style.Position {
+	case "", "top", "center", "bottom", "top_left", "top_right", "bottom_left", "bottom_right":
+	default:
+		return fmt.Errorf("invalid text position: %s", style.Position)
+	}
+	for _, color := range []string{style.FontColor, style.BoxColor} {
+		if color != "" && !hexColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid text color: %s (expected #RRGGBB or RRGGBB)", color)
+		}
+	}
+	if style.FontSize < 0 || style.Margin < 0 {
+		return fmt.Errorf("text font_size and margin must not be negative")
+	}
+	if style.BoxOpacity < 0 || style.BoxOpacity > 1 {
+		return fmt.Errorf("text box_opacity must be between 0 and 1")
+	}
+	return nil
+}
+
+// templateVariablePattern 文字模板变量：{{name}}
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// expandTemplate 替换文字中的{{变量}}
+func expandTemplate(text string, variables map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := templateVariablePattern.FindStringSubmatch(match)[1]
+		return variables[name]
+	})
+}
+
+// validateTemplate 验证文字中引用的变量都已定义
+func validateTemplate(text string, variables map[string]string) error {
+	for _, match := range templateVariablePattern.FindAllStringSubmatch(text, -1) {
+		if _, ok := variables[match[1]]; !ok {
+			return fmt.Errorf("undefined template variable: %s", match[1])
+		}
+	}
+	return nil
+}
+
+// buildTextOverlays 构建文字叠加滤镜链：[label] -> [outLabel]，多个文字依次drawtext
+// height为画面高度（未知时为0，按720计算默认字号）
+func (s *FFmpegService) buildTextOverlays(overlays []model.TextOverlay, variables map[string]string, label string, outLabel string, height int, tempFiles *[]string) (string, error) {
+	if height == 0 {
+		height = 720
+	}
+
+	var filters []string
+	for _, overlay := range overlays {
+		var extra []string
+		if enable := s.enableExpr(overlay.StartTime, overlay.EndTime); enable != "" {
+			extra = append(extra, "enable="+enable)
+		}
+		if alpha := fadeAlphaExpr(overlay.StartTime, overlay.EndTime, overlay.FadeIn, overlay.FadeOut); alpha != "" {
+			extra = append(extra, "alpha="+alpha)
+		}
+
+		style := overlay.TextStyle
+		filter, err := s.buildDrawText(expandTemplate(overlay.Text, variables), &style, height, tempFiles, extra...)
+		if err != nil {
+			return "", err
+		}
+		filters = append(filters, filter)
+	}
+
+	return fmt.Sprintf("[%s]%s[%s]", label, strings.Join(filters, ","), outLabel), nil
+}
+
+// fadeAlphaExpr 构建文字淡入淡出的透明度表达式，淡出需要设置结束时间
+func fadeAlphaExpr(start, end, fadeIn, fadeOut float64) string {
+	expr := "1"
+	if fadeOut > 0 && end > 0 {
+		expr = fmt.Sprintf("if(gt(t,%.3f),(%.3f-t)/%.3f,%s)", end-fadeOut, end, fadeOut, expr)
+	}
+	if fadeIn > 0 {
+		expr = fmt.Sprintf("if(lt(t,%.3f),(t-%.3f)/%.3f,%s)", start+fadeIn, start, fadeIn, expr)
+	}
+	if expr == "1" {
+		return ""
+	}
+	return "'" + expr + "'"
+}
+
+// validateTextOverlays 验证文字叠加参数
+func (s *FFmpegService) validateTextOverlays(overlays []model.TextOverlay, variables map[string]string) error {
+	for i, overlay := range overlays {
+		if strings.TrimSpace(overlay.Text) == "" {
+			return fmt.Errorf("text_overlays[%d]: text is required", i)
+		}
+		if err := validateTemplate(overlay.Text, variables); err != nil {
+			return fmt.Errorf("text_overlays[%d]: %w", i, err)
+		}
+		if err := s.validateTextStyle(&overlay.TextStyle); err != nil {
+			return fmt.Errorf("text_overlays[%d]: %w", i, err)
+		}
+		if overlay.StartTime < 0 || (overlay.EndTime > 0 && overlay.EndTime <= overlay.StartTime) {
+			return fmt.Errorf("text_overlays[%d]: invalid time window %.2f-%.2f", i, overlay.StartTime, overlay.EndTime)
+		}
+		if overlay.FadeIn < 0 || overlay.FadeOut < 0 {
+			return fmt.Errorf("text_overlays[%d]: fade durations must not be negative", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 缩略图产物文件名
+const (
+	ThumbnailPosterFile     = "poster.jpg"
+	ThumbnailStoryboardFile = "storyboard.vtt"
+	thumbnailSpritePattern  = "sprite_%03d.jpg"
+)
+
+// BuildThumbnailsCommand 构建缩略图任务的ffmpeg命令
+// outputPath为任务输出目录下的WebVTT文件路径，封面和雪碧图输出到同一目录
+// 返回值：命令参数、总帧数（雪碧图张数）、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildThumbnailsCommand(params model.TaskInputParams, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	if params.InputPath == "" {
+		return nil, 0, nil, fmt.Errorf("no input video provided")
+	}
+
+	localInputPath, err := s.downloadInput(params.InputPath, &tempFiles)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("download input video failed: %w", err)
+	}
+
+	args, totalFrames, err := s.BuildThumbnailPostCommand(localInputPath, params.Thumbnails, filepath.Dir(outputPath))
+	if err != nil {
+		s.CleanupTempFiles(tempFiles)
+		return nil, 0, nil, err
+	}
+	return args, totalFrames, tempFiles, nil
+}
+
+// BuildThumbnailPostCommand 基于本地视频文件构建缩略图生成命令，同时写出WebVTT预览索引
+// 用于thumbnails任务，也用于其他视频任务完成后的附加步骤
+// 返回值：命令参数、总帧数（雪碧图张数）、错误
+func (s *FFmpegService) BuildThumbnailPostCommand(inputPath string, opts *model.ThumbnailOptions, outputDir string) ([]string, int, error) {
+	var o model.ThumbnailOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Interval <= 0 {
+		o.Interval = 10
+	}
+	if o.Width <= 0 {
+		o.Width = 160
+	}
+	if o.Columns <= 0 {
+		o.Columns = 5
+	}
+	if o.Rows <= 0 {
+		o.Rows = 5
+	}
+
+	info, err := s.parser.GetMediaInfo(inputPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get media info failed: %w", err)
+	}
+	if info.Duration <= 0 || info.Width <= 0 || info.Height <= 0 {
+		return nil, 0, fmt.Errorf("cannot generate thumbnails: unknown video duration or size")
+	}
+
+	if o.PosterTime <= 0 || o.PosterTime >= info.Duration {
+		o.PosterTime = info.Duration * 0.1
+	}
+
+	// 缩略图高度按源视频宽高比计算并取偶数，VTT坐标需要精确值
+	thumbHeight := int(math.Round(float64(o.Width)*float64(info.Height)/float64(info.Width)/2)) * 2
+	thumbCount := int(math.Ceil(info.Duration / o.Interval))
+	perSheet := o.Columns * o.Rows
+	sheetCount := (thumbCount + perSheet - 1) / perSheet
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, 0, fmt.Errorf("create thumbnail output dir failed: %w", err)
+	}
+	vtt := s.buildStoryboardVTT(info.Duration, o, thumbHeight, thumbCount)
+	if err := os.WriteFile(filepath.Join(outputDir, ThumbnailStoryboardFile), []byte(vtt), 0644); err != nil {
+		return nil, 0, fmt.Errorf("write storyboard vtt failed: %w", err)
+	}
+
+	// 雪碧图作为第一个输出，进度按已生成的雪碧图张数计算
+	filterComplex := fmt.Sprintf(
+		"[0:v]split=2[spritein][posterin];"+
+			"[spritein]fps=1/%.3f,scale=%d:%d,tile=%dx%d[sprite];"+
+			"[posterin]select='gte(t,%.3f)'[poster]",
+		o.Interval, o.Width, thumbHeight, o.Columns, o.Rows, o.PosterTime)
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", inputPath,
+		"-filter_complex", filterComplex,
+		"-map", "[sprite]",
+		"-q:v", "3",
+		"-y",
+		filepath.Join(outputDir, thumbnailSpritePattern),
+		"-map", "[poster]",
+		"-frames:v", "1",
+		"-q:v", "2",
+		"-y",
+		filepath.Join(outputDir, ThumbnailPosterFile),
+	}
+
+	return args, sheetCount, nil
+}
+
+// buildStoryboardVTT 生成WebVTT预览索引，每个时间段指向雪碧图中的坐标区域
+func (s *FFmpegService) buildStoryboardVTT(duration float64, o model.ThumbnailOptions, thumbHeight int, thumbCount int) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+
+	perSheet := o.Columns * o.Rows
+	for i := 0; i < thumbCount; i++ {
+		start := float64(i) * o.Interval
+		end := math.Min(start+o.Interval, duration)
+
+		sheet := fmt.Sprintf(thumbnailSpritePattern, i/perSheet+1)
+		pos := i % perSheet
+		x := (pos % o.Columns) * o.Width
+		y := (pos / o.Columns) * thumbHeight
+
+		sb.WriteString(fmt.Sprintf("%s --> %s\n", formatVTTTime(start), formatVTTTime(end)))
+		sb.WriteString(fmt.Sprintf("%s#xywh=%d,%d,%d,%d\n\n", sheet, x, y, o.Width, thumbHeight))
+	}
+	return sb.String()
+}
+
+// ThumbnailArtifacts 列出缩略图目录中的产物，返回 名称 -> 相对文件名
+func (s *FFmpegService) ThumbnailArtifacts(outputDir string) map[string]string {
+	artifacts := make(map[string]string)
+
+	files := []string{ThumbnailPosterFile, ThumbnailStoryboardFile}
+	sprites, _ := filepath.Glob(filepath.Join(outputDir, "sprite_*.jpg"))
+	sort.Strings(sprites)
+	for _, sprite := range sprites {
+		files = append(files, filepath.Base(sprite))
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(outputDir, file)); err != nil {
+			continue
+		}
+		artifacts[strings.TrimSuffix(file, filepath.Ext(file))] = file
+	}
+	return artifacts
+}
+
+// validateThumbnails 验证缩略图参数
+func (s *FFmpegService) validateThumbnails(opts *model.ThumbnailOptions) error {
+	if opts.Interval < 0 || opts.PosterTime < 0 {
+		return fmt.Errorf("thumbnail interval and poster_time must not be negative")
+	}
+	if opts.Width < 0 || opts.Columns < 0 || opts.Rows < 0 {
+		return fmt.Errorf("thumbnail width, columns and rows must not be negative")
+	}
+	if opts.Width%2 != 0 {
+		return fmt.Errorf("thumbnail width must be even")
+	}
+	return nil
+}
+
+// formatVTTTime 格式化WebVTT时间戳 HH:MM:SS.mmm
+func formatVTTTime(seconds float64) string {
+	ms := int(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// 标题卡默认参数
+const (
+	defaultTitleCardDuration = 3.0
+	defaultTitleCardFade     = 0.5
+)
+
+// TitleCardsApplicable 判断任务输出是否需要拼接标题卡（仅单文件视频输出）
+func (s *FFmpegService) TitleCardsApplicable(task *model.Task) bool {
+	if len(task.InputParams.TitleCards) == 0 {
+		return false
+	}
+	switch task.Type {
+	case "gif", "audio_convert", "thumbnails":
+		return false
+	}
+	return !s.IsPackagedOutput(s.TaskOutputFormat(task))
+}
+
+// titleCardsDuration 计算所有标题卡的总时长
+func titleCardsDuration(cards []model.TitleCard) float64 {
+	var total float64
+	for _, card := range cards {
+		if card.Duration == 0 {
+			total += defaultTitleCardDuration
+		} else {
+			total += card.Duration
+		}
+	}
+	return total
+}
+
+// BuildTitleCardsCommand 构建将标题卡拼接到已生成视频首尾的ffmpeg命令
+// 标题卡按已生成视频的分辨率、帧率和音频格式渲染（纯色或图片背景 + 标题文字 + 淡入淡出），再用concat滤镜拼接
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+func (s *FFmpegService) BuildTitleCardsCommand(params model.TaskInputParams, inputPath string, outputPath string) ([]string, int, []string, error) {
+	var tempFiles []string
+
+	info, err := s.parser.GetMediaInfo(inputPath)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("get media info failed: %w", err)
+	}
+	if info.Width == 0 || info.Height == 0 {
+		return nil, 0, nil, fmt.Errorf("output has no video stream")
+	}
+
+	fps := info.FPS
+	if fps == 0 {
+		fps = 25
+	}
+	hasAudio := info.AudioCodec != ""
+	sampleRate := info.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 48000
+	}
+
+	args := []string{
+		"-loglevel", "info",
+		"-stats",
+		"-i", inputPath,
+	}
+
+	var filters []string
+	var startSegments, endSegments []string
+	totalDuration := info.Duration
+
+	for i, card := range params.TitleCards {
+		duration := card.Duration
+		if duration == 0 {
+			duration = defaultTitleCardDuration
+		}
+		totalDuration += duration
+
+		// 背景：图片铺满画面，或纯色
+		videoInput := countInputs(args)
+		cardLabel := fmt.Sprintf("card%d", i)
+		if card.ImagePath != "" {
+			localPath, err := s.downloadInput(card.ImagePath, &tempFiles)
+			if err != nil {
+				s.CleanupTempFiles(tempFiles)
+				return nil, 0, nil, fmt.Errorf("download title card image failed: %w", err)
+			}
+			args = append(args, "-loop", "1", "-framerate", fmt.Sprintf("%.3f", fps), "-t", fmt.Sprintf("%.3f", duration), "-i", localPath)
+			filters = append(filters, s.buildFitFilter(&model.FitOptions{Mode: "cover"}, fmt.Sprintf("%d:v", videoInput), cardLabel+"bg", info.Width, info.Height))
+		} else {
+			color := "black"
+			if card.Background != "" {
+				color = "0x" + strings.TrimPrefix(card.Background, "#")
+			}
+			args = append(args, "-f", "lavfi", "-i", fmt.Sprintf("color=c=%s:s=%dx%d:r=%.3f:d=%.3f", color, info.Width, info.Height, fps, duration))
+			filters = append(filters, fmt.Sprintf("[%d:v]setsar=1[%sbg]", videoInput, cardLabel))
+		}
+
+		chain, err := s.buildTitleCardText(card, params.Variables, info.Height, &tempFiles)
+		if err != nil {
+			s.CleanupTempFiles(tempFiles)
+			return nil, 0, nil, err
+		}
+
+		fade := card.Fade
+		if fade == 0 {
+			fade = defaultTitleCardFade
+		}
+		chain = append(chain, fmt.Sprintf("fade=t=in:st=0:d=%.2f", fade), fmt.Sprintf("fade=t=out:st=%.3f:d=%.2f", duration-fade, fade))
+		filters = append(filters, fmt.Sprintf("[%sbg]format=yuv420p,%s[%s]", cardLabel, strings.Join(chain, ","), cardLabel))
+
+		segment := "[" + cardLabel + "]"
+		if hasAudio {
+			audioInput := countInputs(args)
+			args = append(args, "-f", "lavfi", "-t", fmt.Sprintf("%.3f", duration), "-i", fmt.Sprintf("anullsrc=r=%d:cl=stereo", sampleRate))
+			segment += fmt.Sprintf("[%d:a]", audioInput)
+		}
+
+		if card.Placement == "end" {
+			endSegments = append(endSegments, segment)
+		} else {
+			startSegments = append(startSegments, segment)
+		}
+	}
+
+	// 主视频统一像素格式（以及音频格式），按片头 -> 主视频 -> 片尾顺序拼接
+	filters = append(filters, "[0:v]setsar=1,format=yuv420p[main]")
+	mainSegment := "[main]"
+	if hasAudio {
+		filters = append(filters, fmt.Sprintf("[0:a]aformat=sample_rates=%d:channel_layouts=stereo[maina]", sampleRate))
+		mainSegment += "[maina]"
+	}
+
+	segments := append(append(startSegments, mainSegment), endSegments...)
+	concat := fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", strings.Join(segments, ""), len(segments))
+	if hasAudio {
+		concat = fmt.Sprintf("%sconcat=n=%d:v=1:a=1[v][a]", strings.Join(segments, ""), len(segments))
+	}
+	filters = append(filters, concat)
+
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[v]",
+	)
+	if hasAudio {
+		args = append(args, "-map", "[a]")
+	}
+
+	// 拼接需要重新编码，流复制参数不适用
+	videoCodec := s.getVideoCodec(params.VideoCodec)
+	if videoCodec == "copy" {
+		videoCodec = "libx264"
+	}
+	args = append(args,
+		"-c:v", videoCodec,
+		"-preset", "ultrafast",
+		"-b:v", s.getVideoBitrate(params.VideoBitrate),
+		"-pix_fmt", "yuv420p",
+	)
+	if hasAudio {
+		audioCodec := s.getAudioCodec(params.AudioCodec)
+		if audioCodec == "copy" {
+			audioCodec = "aac"
+		}
+		args = append(args, "-c:a", audioCodec, "-b:a", s.getAudioBitrate(params.AudioBitrate))
+	}
+
+	outputFormat := s.getOutputFormat(params.OutputFormat)
+	if outputFormat == "mp4" || outputFormat == "mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args,
+		"-f", outputFormat,
+		"-y",
+		outputPath,
+	)
+
+	return args, int(totalDuration * fps), tempFiles, nil
+}
+
+// buildTitleCardText 构建标题卡的标题和副标题drawtext滤镜
+func (s *FFmpegService) buildTitleCardText(card model.TitleCard, variables map[string]string, height int, tempFiles *[]string) ([]string, error) {
+	var chain []string
+
+	texts := []struct {
+		text     string
+		style    *model.TextStyle
+		position string
+		fontSize int
+	}{
+		{card.Title, card.TitleStyle, "center", height / 10},
+		{card.Subtitle, card.SubtitleStyle, "bottom", height / 20},
+	}
+
+	for _, t := range texts {
+		if t.text == "" {
+			continue
+		}
+		style := model.TextStyle{Position: t.position, FontSize: t.fontSize}
+		if t.style != nil {
+			style = *t.style
+			if style.Position == "" {
+				style.Position = t.position
+			}
+			if style.FontSize == 0 {
+				style.FontSize = t.fontSize
+			}
+		}
+
+		filter, err := s.buildDrawText(expandTemplate(t.text, variables), &style, height, tempFiles)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, filter)
+	}
+	return chain, nil
+}
+
+// validateTitleCards 验证标题卡参数
+func (s *FFmpegService) validateTitleCards(cards []model.TitleCard, variables map[string]string) error {
+	for i, card := range cards {
+		switch card.Placement {
+		case "", "start", "end":
+		default:
+			return fmt.Errorf("title_cards[%d]: invalid placement: %s", i, card.Placement)
+		}
+		if card.Duration < 0 || card.Fade < 0 {
+			return fmt.Errorf("title_cards[%d]: duration and fade must not be negative", i)
+		}
+
+		duration, fade := card.Duration, card.Fade
+		if duration == 0 {
+			duration = defaultTitleCardDuration
+		}
+		if fade == 0 {
+			fade = defaultTitleCardFade
+		}
+		if fade*2 > duration {
+			return fmt.Errorf("title_cards[%d]: fade %.2f is too long for duration %.2f", i, fade, duration)
+		}
+
+		if card.Background != "" && !hexColorPattern.MatchString(card.Background) {
+			return fmt.Errorf("title_cards[%d]: invalid background color: %s (expected #RRGGBB or RRGGBB)", i, card.Background)
+		}
+		if card.ImagePath != "" {
+			if err := s.parser.ValidateFile(card.ImagePath); err != nil {
+				return fmt.Errorf("title_cards[%d]: invalid image: %w", i, err)
+			}
+		}
+
+		for _, text := range []string{card.Title, card.Subtitle} {
+			if err := validateTemplate(text, variables); err != nil {
+				return fmt.Errorf("title_cards[%d]: %w", i, err)
+			}
+		}
+		for _, style := range []*model.TextStyle{card.TitleStyle, card.SubtitleStyle} {
+			if style == nil {
+				continue
+			}
+			if err := s.validateTextStyle(style); err != nil {
+				return fmt.Errorf("title_cards[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
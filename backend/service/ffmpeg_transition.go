@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// TransitionInfo 转场效果说明，供前端构建转场选择器
+type TransitionInfo struct {
+	Name     string `json:"name"`     // xfade转场名称
+	Label    string `json:"label"`    // 显示名称
+	Category string `json:"category"` // 分类：fade, wipe, slide, smooth, shape, slice, other
+}
+
+// transitionCatalog 支持的xfade转场（ffmpeg 4.4及以上均可用）
+var transitionCatalog = []TransitionInfo{
+	{Name: "fade", Label: "淡入淡出", Category: "fade"},
+	{Name: "fadeblack", Label: "黑场过渡", Category: "fade"},
+	{Name: "fadewhite", Label: "白场过渡", Category: "fade"},
+	{Name: "fadegrays", Label: "灰度过渡", Category: "fade"},
+	{Name: "dissolve", Label: "溶解", Category: "fade"},
+	{Name: "distance", Label: "距离渐变", Category: "fade"},
+	{Name: "wipeleft", Label: "向左擦除", Category: "wipe"},
+	{Name: "wiperight", Label: "向右擦除", Category: "wipe"},
+	{Name: "wipeup", Label: "向上擦除", Category: "wipe"},
+	{Name: "wipedown", Label: "向下擦除", Category: "wipe"},
+	{Name: "wipetl", Label: "向左上擦除", Category: "wipe"},
+	{Name: "wipetr", Label: "向右上擦除", Category: "wipe"},
+	{Name: "wipebl", Label: "向左下擦除", Category: "wipe"},
+	{Name: "wipebr", Label: "向右下擦除", Category: "wipe"},
+	{Name: "slideleft", Label: "向左滑动", Category: "slide"},
+	{Name: "slideright", Label: "向右滑动", Category: "slide"},
+	{Name: "slideup", Label: "向上滑动", Category: "slide"},
+	{Name: "slidedown", Label: "向下滑动", Category: "slide"},
+	{Name: "smoothleft", Label: "向左平滑", Category: "smooth"},
+	{Name: "smoothright", Label: "向右平滑", Category: "smooth"},
+	{Name: "smoothup", Label: "向上平滑", Category: "smooth"},
+	{Name: "smoothdown", Label: "向下平滑", Category: "smooth"},
+	{Name: "circlecrop", Label: "圆形裁切", Category: "shape"},
+	{Name: "rectcrop", Label: "矩形裁切", Category: "shape"},
+	{Name: "circleopen", Label: "圆形展开", Category: "shape"},
+	{Name: "circleclose", Label: "圆形收拢", Category: "shape"},
+	{Name: "vertopen", Label: "垂直展开", Category: "shape"},
+	{Name: "vertclose", Label: "垂直收拢", Category: "shape"},
+	{Name: "horzopen", Label: "水平展开", Category: "shape"},
+	{Name: "horzclose", Label: "水平收拢", Category: "shape"},
+	{Name: "radial", Label: "径向扫过", Category: "shape"},
+	{Name: "diagtl", Label: "左上对角", Category: "shape"},
+	{Name: "diagtr", Label: "右上对角", Category: "shape"},
+	{Name: "diagbl", Label: "左下对角", Category: "shape"},
+	{Name: "diagbr", Label: "右下对角", Category: "shape"},
+	{Name: "hlslice", Label: "水平向左切片", Category: "slice"},
+	{Name: "hrslice", Label: "水平向右切片", Category: "slice"},
+	{Name: "vuslice", Label: "垂直向上切片", Category: "slice"},
+	{Name: "vdslice", Label: "垂直向下切片", Category: "slice"},
+	{Name: "pixelize", Label: "像素化", Category: "other"},
+	{Name: "hblur", Label: "水平模糊", Category: "other"},
+	{Name: "squeezeh", Label: "水平挤压", Category: "other"},
+	{Name: "squeezev", Label: "垂直挤压", Category: "other"},
+	{Name: "zoomin", Label: "放大进入", Category: "other"},
+}
+
+// transitionAliases 兼容旧版本文档中的转场名称
+var transitionAliases = map[string]string{
+	"slide": "slideleft",
+}
+
+// SupportedTransitions 获取支持的转场列表（不含none）
+func SupportedTransitions() []TransitionInfo {
+	return transitionCatalog
+}
+
+// resolveTransition 解析转场名称：空值默认fade，兼容旧名称
+func (s *FFmpegService) resolveTransition(name string) string {
+	if name == "" {
+		return "fade"
+	}
+	if alias, ok := transitionAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// slideTransitions 获取每个衔接处的转场：优先使用transitions中的对应项，否则使用transition_type
+func (s *FFmpegService) slideTransitions(params model.TaskInputParams, numImages int) []string {
+	if numImages < 2 {
+		return nil
+	}
+	transitions := make([]string, numImages-1)
+	for i := range transitions {
+		name := params.TransitionType
+		if i < len(params.Transitions) && params.Transitions[i] != "" {
+			name = params.Transitions[i]
+		}
+		transitions[i] = s.resolveTransition(name)
+	}
+	return transitions
+}
+
+// validateTransition 验证转场名称，未知名称直接拒绝而不是静默回退
+func (s *FFmpegService) validateTransition(name string) error {
+	if name == "" || name == "none" {
+		return nil
+	}
+	name = s.resolveTransition(name)
+	for _, t := range transitionCatalog {
+		if t.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported transition: %s (see GET /api/transitions for the supported list)", name)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fangzio/ffmpeg-platform/model"
+)
+
+// visualizerStyles 各可视化类型支持的样式，第一项为默认值
+// waves/freqs/vectorscope对应滤镜的mode参数，spectrum对应showspectrum的color配色方案
+var visualizerStyles = map[string][]string{
+	"waves":       {"cline", "point", "line", "p2p"},
+	"freqs":       {"bar", "line", "dot"},
+	"spectrum":    {"intensity", "channel", "rainbow", "moreland", "nebulae", "fire", "fiery", "fruit", "cool", "magma", "green", "viridis", "plasma", "cividis", "terrain"},
+	"vectorscope": {"lissajous", "lissajous_xy", "polar"},
+}
+
+// visualizerType 获取可视化类型，默认waves
+func visualizerType(viz *model.VisualizerOptions) string {
+	if viz.Type == "" {
+		return "waves"
+	}
+	return viz.Type
+}
+
+// buildVisualizerFilter 构建音频可视化滤镜图片段：由音频流[audio]生成动画并叠加到[in]上，输出[out]
+// frameWidth/frameHeight为叠加目标画面的尺寸，用于将相对尺寸换算为像素
+func (s *FFmpegService) buildVisualizerFilter(viz *model.VisualizerOptions, audio, in, out string, frameWidth, frameHeight, fps int) string {
+	vizType := visualizerType(viz)
+	style := viz.Style
+	if style == "" {
+		style = visualizerStyles[vizType][0]
+	}
+
+	widthRatio, heightRatio := viz.Width, viz.Height
+	if widthRatio == 0 {
+		widthRatio = 0.8
+	}
+	if heightRatio == 0 {
+		heightRatio = 0.25
+	}
+	size := fmt.Sprintf("%dx%d", int(float64(frameWidth)*widthRatio)&^1, int(float64(frameHeight)*heightRatio)&^1)
+
+	color := strings.TrimPrefix(viz.Color, "#")
+	if color == "" {
+		color = "FFFFFF"
+	}
+
+	var source string
+	switch vizType {
+	case "freqs":
+		source = fmt.Sprintf("showfreqs=s=%s:mode=%s:colors=0x%s", size, style, color)
+	case "spectrum":
+		source = fmt.Sprintf("showspectrum=s=%s:slide=scroll:color=%s", size, style)
+	case "vectorscope":
+		// avectorscope没有颜色参数，用各通道对比度近似着色
+		r, _ := strconv.ParseUint(color[0:2], 16, 8)
+		g, _ := strconv.ParseUint(color[2:4], 16, 8)
+		b, _ := strconv.ParseUint(color[4:6], 16, 8)
+		source = fmt.Sprintf("avectorscope=s=%s:mode=%s:rate=%d:rc=%d:gc=%d:bc=%d", size, style, fps, r, g, b)
+	default: // waves
+		source = fmt.Sprintf("showwaves=s=%s:mode=%s:rate=%d:colors=0x%s", size, style, fps, color)
+	}
+
+	// 统一帧率并转为带透明通道的格式，便于叠加和调整不透明度
+	chain := fmt.Sprintf("[%s]%s,fps=%d,format=rgba", audio, source, fps)
+	if viz.Opacity > 0 && viz.Opacity < 1 {
+		chain += fmt.Sprintf(",colorchannelmixer=aa=%.2f", viz.Opacity)
+	}
+
+	margin := viz.Margin
+	if margin == 0 {
+		margin = 40
+	}
+	// 默认位于画面底部居中
+	position := viz.Position
+	if position == "" {
+		position = "bottom"
+	}
+	x, y := s.overlayPosition(position, margin)
+
+	return fmt.Sprintf("%s[viz];[%s][viz]overlay=x=%s:y=%s[%s]", chain, in, x, y, out)
+}
+
+// validateVisualizer 验证音频可视化参数
+func (s *FFmpegService) validateVisualizer(viz *model.VisualizerOptions) error {
+	vizType := visualizerType(viz)
+	styles, ok := visualizerStyles[vizType]
+	if !ok {
+		return fmt.Errorf("invalid visualizer type: %s (expected waves, freqs, spectrum or vectorscope)", viz.Type)
+	}
+	if viz.Style != "" {
+		valid := false
+		for _, style := range styles {
+			if style == viz.Style {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid visualizer style for %s: %s (supported: %s)", vizType, viz.Style, strings.Join(styles, ", "))
+		}
+	}
+
+	if viz.Color != "" && !hexColorPattern.MatchString(viz.Color) {
+		return fmt.Errorf("invalid visualizer color: %s (expected #RRGGBB or RRGGBB)", viz.Color)
+	}
+	if viz.Width < 0 || viz.Width > 1 || viz.Height < 0 || viz.Height > 1 {
+		return fmt.Errorf("visualizer width and height must be between 0 and 1")
+	}
+	if viz.Opacity < 0 || viz.Opacity > 1 {
+		return fmt.Errorf("visualizer opacity must be between 0 and 1")
+	}
+	if viz.Margin < 0 {
+		return fmt.Errorf("visualizer margin must not be negative")
+	}
+
+	switch viz.Position {
+	case "", "top", "center", "bottom", "top_left", "top_right", "bottom_left", "bottom_right":
+	default:
+		return fmt.Errorf("invalid visualizer position: %s", viz.Position)
+	}
+	return nil
+}
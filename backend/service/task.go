@@ -35,6 +35,13 @@ func (s *TaskService) CreateTask(taskType string, params model.TaskInputParams)
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	if err := s.ffmpegService.ValidateTaskOptions(taskType, params); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	// 展开输出预设，任务记录中保存实际使用的参数
+	params = s.ffmpegService.ApplyPreset(taskType, params)
+
 	task := &model.Task{
 		ID:          uuid.New().String(),
 		Type:        taskType,
@@ -112,6 +119,17 @@ func (s *TaskService) CompleteTask(taskID string, result TaskResult) error {
 		"output_url":     result.OutputURL,
 		"updated_at":     time.Now(),
 	}
+	if len(result.Artifacts) > 0 {
+		artifacts, _ := json.Marshal(result.Artifacts)
+		updates["artifacts"] = string(artifacts)
+	}
+	if result.Loudness != nil {
+		loudness, _ := json.Marshal(result.Loudness)
+		updates["loudness"] = string(loudness)
+	}
+	if result.ErrorMessage != "" {
+		updates["error_message"] = result.ErrorMessage
+	}
 
 	return s.db.Model(&model.Task{}).Where("id = ?", taskID).Updates(updates).Error
 }
@@ -136,5 +154,8 @@ type TaskResult struct {
 	StderrLog     string
 	OutputFile    string
 	OutputURL     string
-	TotalFrames   int // 总帧数
+	TotalFrames   int                   // 总帧数
+	Artifacts     map[string]string     // 附加产物 名称 -> URL
+	Loudness      *model.LoudnessReport // 响度标准化报告
+	ErrorMessage  string                // 附加步骤失败原因（主任务仍标记为完成）
 }
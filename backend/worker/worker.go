@@ -10,6 +10,9 @@ import (
 	"github.com/fangzio/ffmpeg-platform/pkg/storage"
 	"github.com/fangzio/ffmpeg-platform/service"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -87,6 +90,28 @@ func (w *Worker) ProcessTask(ctx context.Context, t *asynq.Task) error {
 			done <- w.processImageAudioToVideo(ctx, task)
 		case "image_slideshow":
 			done <- w.processImageSlideshow(ctx, task)
+		case "transcode":
+			done <- w.processTranscode(ctx, task)
+		case "clip":
+			done <- w.processClip(ctx, task)
+		case "concat":
+			done <- w.processConcat(ctx, task)
+		case "watermark":
+			done <- w.processWatermark(ctx, task)
+		case "thumbnails":
+			done <- w.processThumbnails(ctx, task)
+		case "gif":
+			done <- w.processGIF(ctx, task)
+		case "audio_convert":
+			done <- w.processAudioConvert(ctx, task)
+		case "compose":
+			done <- w.processCompose(ctx, task)
+		case "retime":
+			done <- w.processRetime(ctx, task)
+		case "reframe":
+			done <- w.processReframe(ctx, task)
+		case "chromakey":
+			done <- w.processChromaKey(ctx, task)
 		default:
 			done <- fmt.Errorf("unknown task type: %s", task.Type)
 		}
@@ -113,71 +138,260 @@ func (w *Worker) ProcessTask(ctx context.Context, t *asynq.Task) error {
 }
 
 // processImageAudioToVideo 处理图片+音频生成视频任务
-func (w *Worker) processImageAudioToVideo(ctx context.Context, task *model.Task) (err error) {
+func (w *Worker) processImageAudioToVideo(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "image+audio to video", w.ffmpegService.BuildImageAudioToVideoCommand)
+}
+
+// processImageSlideshow 处理多图片轮播视频任务
+func (w *Worker) processImageSlideshow(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "image slideshow", w.ffmpegService.BuildImageSlideshowCommand)
+}
+
+// processTranscode 处理视频转码任务
+func (w *Worker) processTranscode(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "transcode", w.ffmpegService.BuildTranscodeCommand)
+}
+
+// processClip 处理视频裁剪任务
+func (w *Worker) processClip(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "clip", w.ffmpegService.BuildClipCommand)
+}
+
+// processConcat 处理视频拼接任务
+func (w *Worker) processConcat(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "concat", w.ffmpegService.BuildConcatCommand)
+}
+
+// processThumbnails 处理缩略图（封面、雪碧图、WebVTT）任务
+func (w *Worker) processThumbnails(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "thumbnails", w.ffmpegService.BuildThumbnailsCommand)
+}
+
+// processGIF 处理动图（GIF/动态WebP）任务
+func (w *Worker) processGIF(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "gif", w.ffmpegService.BuildGIFCommand)
+}
+
+// processAudioConvert 处理音频提取/转换任务（按时长计算进度）
+func (w *Worker) processAudioConvert(ctx context.Context, task *model.Task) error {
+	return w.runDurationTask(ctx, task, "audio convert", w.ffmpegService.BuildAudioConvertCommand)
+}
+
+// processCompose 处理画面合成（画中画/多宫格）任务
+func (w *Worker) processCompose(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "compose", w.ffmpegService.BuildComposeCommand)
+}
+
+// processRetime 处理变速/倒放任务
+func (w *Worker) processRetime(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "retime", w.ffmpegService.BuildRetimeCommand)
+}
+
+// processReframe 处理裁剪/旋转/翻转任务
+func (w *Worker) processReframe(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "reframe", w.ffmpegService.BuildReframeCommand)
+}
+
+// processChromaKey 处理绿幕抠像任务
+func (w *Worker) processChromaKey(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "chromakey", w.ffmpegService.BuildChromaKeyCommand)
+}
+
+// processWatermark 处理视频加水印任务
+func (w *Worker) processWatermark(ctx context.Context, task *model.Task) error {
+	return w.runFFmpegTask(ctx, task, "watermark", w.ffmpegService.BuildWatermarkCommand)
+}
+
+// commandBuilder 构建ffmpeg命令的函数签名（与FFmpegService.Build*Command一致）
+// 返回值：命令参数、总帧数、临时文件列表（需要清理）、错误
+type commandBuilder func(params model.TaskInputParams, outputPath string) ([]string, int, []string, error)
+
+// durationCommandBuilder 按时长计算进度的命令构建函数签名（纯音频输出没有视频帧）
+// 返回值：命令参数、总时长（秒）、临时文件列表（需要清理）、错误
+type durationCommandBuilder func(params model.TaskInputParams, outputPath string) ([]string, float64, []string, error)
+
+// preparedCommand 构建完成、待执行的ffmpeg命令
+type preparedCommand struct {
+	args          []string
+	tempFiles     []string
+	totalFrames   int     // 按帧数计算进度
+	totalDuration float64 // 按时长计算进度（totalFrames为0时使用）
+}
+
+// runFFmpegTask 单条ffmpeg命令任务的通用处理流程（按帧数计算进度）
+func (w *Worker) runFFmpegTask(ctx context.Context, task *model.Task, name string, build commandBuilder) error {
+	return w.runCommandTask(ctx, task, name, func(outputPath string) (*preparedCommand, error) {
+		args, totalFrames, tempFiles, err := build(task.InputParams, outputPath)
+		if err != nil {
+			return nil, err
+		}
+		args, totalFrames = w.ffmpegService.AppendPresetLimits(task.InputParams, args, totalFrames)
+		return &preparedCommand{args: args, tempFiles: tempFiles, totalFrames: totalFrames}, nil
+	})
+}
+
+// runDurationTask 单条ffmpeg命令任务的通用处理流程（按时长计算进度）
+func (w *Worker) runDurationTask(ctx context.Context, task *model.Task, name string, build durationCommandBuilder) error {
+	return w.runCommandTask(ctx, task, name, func(outputPath string) (*preparedCommand, error) {
+		args, totalDuration, tempFiles, err := build(task.InputParams, outputPath)
+		if err != nil {
+			return nil, err
+		}
+		return &preparedCommand{args: args, tempFiles: tempFiles, totalDuration: totalDuration}, nil
+	})
+}
+
+// runCommandTask 构建命令 -> 执行并推送进度 -> 上传输出 -> 更新任务状态
+func (w *Worker) runCommandTask(ctx context.Context, task *model.Task, name string, prepare func(outputPath string) (*preparedCommand, error)) (err error) {
 	// 添加 panic 恢复机制，确保任务状态能正确更新
 	defer func() {
 		if r := recover(); r != nil {
-			errMsg := fmt.Sprintf("Task panic: %v", r)
 			log.Printf("Task %s panic: %v", task.ID, r)
-
-			// 更新任务为失败状态
-			w.taskService.FailTask(task.ID, "", "", "", errMsg)
-			w.broadcastProgress(task.ID, model.TaskProgress{
-				TaskID:  task.ID,
-				Status:  model.TaskStatusFailed,
-				Message: errMsg,
-			})
-
-			err = fmt.Errorf("%s", errMsg)
+			err = w.failTask(task.ID, fmt.Sprintf("Task panic: %v", r))
 		}
 	}()
 
-	log.Printf("Task %s: Starting image+audio to video processing", task.ID)
+	log.Printf("Task %s: Starting %s processing", task.ID, name)
 
 	// 生成输出路径
-	outputPath := w.ffmpegService.GenerateOutputPath(task.ID, task.InputParams.OutputFormat)
+	outputFormat := w.ffmpegService.TaskOutputFormat(task)
+	outputPath := w.ffmpegService.GenerateOutputPath(task.ID, outputFormat)
 	log.Printf("Task %s: Output path: %s", task.ID, outputPath)
 
+	// 目录形式的输出（HLS/DASH/缩略图）需要预先创建任务目录
+	if w.ffmpegService.IsPackagedOutput(outputFormat) {
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return w.failTask(task.ID, fmt.Sprintf("Failed to create output dir: %v", err))
+		}
+	}
+
 	// 构建ffmpeg命令
-	args, totalFrames, tempFiles, err := w.ffmpegService.BuildImageAudioToVideoCommand(task.InputParams, outputPath)
+	cmd, err := prepare(outputPath)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to build ffmpeg command: %v", err)
-		log.Printf("Task %s: %s", task.ID, errMsg)
-		w.taskService.FailTask(task.ID, "", "", "", errMsg)
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:  task.ID,
-			Status:  model.TaskStatusFailed,
-			Message: errMsg,
-		})
-		return fmt.Errorf("%s", errMsg)
+		return w.failTask(task.ID, fmt.Sprintf("Failed to build ffmpeg command: %v", err))
 	}
 
 	// 确保临时文件在函数结束时被清理
 	defer func() {
-		if len(tempFiles) > 0 {
-			log.Printf("Task %s: Cleaning up %d temporary files", task.ID, len(tempFiles))
-			w.ffmpegService.CleanupTempFiles(tempFiles)
+		if len(cmd.tempFiles) > 0 {
+			log.Printf("Task %s: Cleaning up %d temporary files", task.ID, len(cmd.tempFiles))
+			w.ffmpegService.CleanupTempFiles(cmd.tempFiles)
 		}
 	}()
 
-	log.Printf("Task %s: Total frames: %d, Command: ffmpeg %v", task.ID, totalFrames, args)
+	log.Printf("Task %s: Total frames: %d, total duration: %.2fs, Command: ffmpeg %v", task.ID, cmd.totalFrames, cmd.totalDuration, cmd.args)
+
+	// 执行ffmpeg命令
+	log.Printf("Task %s: Starting ffmpeg execution", task.ID)
+	callback := w.progressCallback(task.ID, progressPrefix(task.Type, name), cmd.totalFrames)
+	var result *ffmpeg.ExecuteResult
+	if cmd.totalFrames == 0 && cmd.totalDuration > 0 {
+		result = w.ffmpegService.ExecuteWithDuration(ctx, cmd.args, cmd.totalDuration, callback)
+	} else {
+		result = w.ffmpegService.ExecuteWithProgress(ctx, cmd.args, cmd.totalFrames, callback)
+	}
+	log.Printf("Task %s: FFmpeg execution finished, success: %v", task.ID, result.Success)
+
+	if !result.Success {
+		return w.failExecution(task.ID, result)
+	}
+
+	// 标题卡：拼接到输出首尾（重新编码整个输出），失败时任务失败
+	if w.ffmpegService.TitleCardsApplicable(task) {
+		result = w.applyTitleCards(ctx, task, outputPath, result)
+		if !result.Success {
+			return w.failExecution(task.ID, result)
+		}
+	}
+
+	w.completeTask(ctx, task, outputPath, result, cmd.totalFrames)
+	return nil
+}
+
+// applyTitleCards 标题卡附加步骤：生成带片头/片尾的新文件并替换原输出
+// 返回合并了两次执行的命令、filter graph和日志的结果，保证完整记录可回放
+func (w *Worker) applyTitleCards(ctx context.Context, task *model.Task, outputPath string, result *ffmpeg.ExecuteResult) *ffmpeg.ExecuteResult {
+	log.Printf("Task %s: Adding %d title cards", task.ID, len(task.InputParams.TitleCards))
+	w.broadcastProgress(task.ID, model.TaskProgress{
+		TaskID:   task.ID,
+		Status:   model.TaskStatusProcessing,
+		Progress: 100,
+		Message:  "Adding title cards...",
+	})
+
+	ext := filepath.Ext(outputPath)
+	titledPath := strings.TrimSuffix(outputPath, ext) + "_titled" + ext
+
+	merged := *result
+	args, totalFrames, tempFiles, err := w.ffmpegService.BuildTitleCardsCommand(task.InputParams, outputPath, titledPath)
+	if err != nil {
+		merged.Success = false
+		merged.ErrorMessage = fmt.Sprintf("Failed to build title cards command: %v", err)
+		return &merged
+	}
+	defer w.ffmpegService.CleanupTempFiles(tempFiles)
+	args, totalFrames = w.ffmpegService.AppendTitleCardPresetLimits(task.InputParams, args, totalFrames)
+
+	cardResult := w.ffmpegService.ExecuteWithProgress(ctx, args, totalFrames, nil)
+	merged.Command += "\n" + cardResult.Command
+	merged.FilterGraph += "\n" + cardResult.FilterGraph
+	merged.StderrLog += "\n=== TITLE CARDS ===\n" + cardResult.StderrLog
+	merged.Duration += cardResult.Duration
+	merged.Success = cardResult.Success
+	merged.ErrorMessage = cardResult.ErrorMessage
+
+	if cardResult.Success {
+		if err := os.Rename(titledPath, outputPath); err != nil {
+			merged.Success = false
+			merged.ErrorMessage = fmt.Sprintf("Failed to replace output with title cards version: %v", err)
+		}
+	} else {
+		os.Remove(titledPath)
+	}
+	return &merged
+}
+
+// progressPrefix 获取进度消息前缀，图片类任务保持原有的消息格式
+func progressPrefix(taskType, name string) string {
+	switch taskType {
+	case "image_audio_to_video":
+		return "Processing"
+	case "image_slideshow":
+		return "Processing slideshow"
+	default:
+		return "Processing " + name
+	}
+}
+
+// completedMessage 获取任务完成消息，图片类任务保持原有的消息格式
+func completedMessage(taskType string) string {
+	if taskType == "image_slideshow" {
+		return "Slideshow video completed successfully"
+	}
+	return "Task completed successfully"
+}
+
+// progressCallback 创建进度回调：广播进度到WebSocket并更新数据库
+func (w *Worker) progressCallback(taskID string, prefix string, totalFrames int) ffmpeg.ProgressCallback {
+	return func(progress ffmpeg.Progress) {
+		message := fmt.Sprintf("%s: %.1f%% (Frame %d/%d, Speed: %.2fx)", prefix, progress.Progress, progress.Frame, totalFrames, progress.Speed)
+		if totalFrames == 0 {
+			// 按时长计算进度的任务没有帧数
+			message = fmt.Sprintf("%s: %.1f%% (Time %s, Speed: %.2fx)", prefix, progress.Progress, progress.Time, progress.Speed)
+		}
 
-	// 创建进度回调
-	progressCallback := func(progress ffmpeg.Progress) {
-		// 广播进度到WebSocket
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
+		w.broadcastProgress(taskID, model.TaskProgress{
+			TaskID:       taskID,
 			Status:       model.TaskStatusProcessing,
 			Progress:     progress.Progress,
 			CurrentFrame: progress.Frame,
 			TotalFrames:  totalFrames,
 			ETA:          progress.ETA,
-			Message:      fmt.Sprintf("Processing: %.1f%% (Frame %d/%d, Speed: %.2fx)", progress.Progress, progress.Frame, totalFrames, progress.Speed),
+			Message:      message,
 		})
 
-		// 更新数据库
-		w.taskService.UpdateTaskProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
+		w.taskService.UpdateTaskProgress(taskID, model.TaskProgress{
+			TaskID:       taskID,
 			Status:       model.TaskStatusProcessing,
 			Progress:     progress.Progress,
 			CurrentFrame: progress.Frame,
@@ -185,260 +399,217 @@ func (w *Worker) processImageAudioToVideo(ctx context.Context, task *model.Task)
 			ETA:          progress.ETA,
 		})
 	}
+}
 
-	// 执行ffmpeg命令
-	log.Printf("Task %s: Starting ffmpeg execution", task.ID)
-	result := w.ffmpegService.ExecuteWithProgress(ctx, args, totalFrames, progressCallback)
-	log.Printf("Task %s: FFmpeg execution finished, success: %v", task.ID, result.Success)
+// completeTask 上传输出文件并将任务标记为完成
+func (w *Worker) completeTask(ctx context.Context, task *model.Task, outputPath string, result *ffmpeg.ExecuteResult, totalFrames int) {
+	// 任务成功 - 在标记完成前，强制广播100%进度
+	log.Printf("Task %s: FFmpeg succeeded, broadcasting final progress", task.ID)
+	w.broadcastProgress(task.ID, model.TaskProgress{
+		TaskID:       task.ID,
+		Status:       model.TaskStatusProcessing,
+		Progress:     100,
+		CurrentFrame: totalFrames,
+		TotalFrames:  totalFrames,
+		ETA:          0,
+		Message:      "Processing completed, finalizing...",
+	})
 
-	if result.Success {
-		// 任务成功 - 在标记完成前，强制广播100%进度
-		log.Printf("Task %s: FFmpeg succeeded, broadcasting final progress", task.ID)
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			ETA:          0,
-			Message:      "Processing completed, finalizing...",
-		})
+	// 更新数据库进度为100%
+	w.taskService.UpdateTaskProgress(task.ID, model.TaskProgress{
+		TaskID:       task.ID,
+		Status:       model.TaskStatusProcessing,
+		Progress:     100,
+		CurrentFrame: totalFrames,
+		TotalFrames:  totalFrames,
+		ETA:          0,
+	})
 
-		// 更新数据库进度为100%
-		w.taskService.UpdateTaskProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			ETA:          0,
-		})
+	artifacts := make(map[string]string)
 
-		// 生成输出文件URL
-		outputURL := ""
-		outputFilename := w.ffmpegService.GenerateOutputName(task.ID, task.InputParams.OutputFormat)
-
-		// 如果是七牛云存储，上传到云端
-		if w.config.Storage.Type == "qiniu" && w.config.Qiniu.Enabled {
-			log.Printf("Task %s: Uploading to Qiniu cloud storage", task.ID)
-			// 生成七牛云存储key（使用outputs目录前缀）
-			key := fmt.Sprintf("outputs/%s", outputFilename)
-			cloudURL, err := w.storage.UploadFile(outputPath, key)
-			if err != nil {
-				log.Printf("Task %s: Warning - failed to upload output to cloud: %v", task.ID, err)
-				// 上传失败不影响任务完成，使用本地路径
-				outputURL = fmt.Sprintf("/api/outputs/%s", outputFilename)
-			} else {
-				log.Printf("Task %s: Upload success, URL: %s", task.ID, cloudURL)
-				outputURL = cloudURL
-				// 删除本地临时文件
-				if err := w.storage.DeleteLocalFile(outputPath); err != nil {
-					log.Printf("Task %s: Warning - failed to delete local output file %s: %v", task.ID, outputPath, err)
-				}
-			}
-		} else {
-			outputURL = fmt.Sprintf("/api/outputs/%s", outputFilename)
+	// 缩略图附加步骤：需要在上传（删除本地文件）之前基于本地输出生成
+	// 失败不影响主任务完成，但记录在任务的错误信息和完成消息中
+	var postErr string
+	if task.InputParams.Thumbnails != nil && task.Type != "thumbnails" {
+		if err := w.generateThumbnails(ctx, task.ID, outputPath, task.InputParams.Thumbnails, artifacts); err != nil {
+			log.Printf("Task %s: Warning - %v", task.ID, err)
+			postErr = err.Error()
 		}
+	}
 
-		// 任务成功
-		log.Printf("Task %s: Marking task as completed", task.ID)
-		w.taskService.CompleteTask(task.ID, service.TaskResult{
-			FFmpegCommand: result.Command,
-			FilterGraph:   result.FilterGraph,
-			StderrLog:     result.StderrLog,
-			OutputFile:    outputPath,
-			OutputURL:     outputURL,
-			TotalFrames:   totalFrames, // 传入总帧数
-		})
-
-		// 广播完成消息（最终状态）
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusCompleted,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			Message:      "Task completed successfully",
-		})
-
-		// 短暂延迟确保WebSocket消息发送完成
-		time.Sleep(100 * time.Millisecond)
+	// 生成输出文件URL
+	outputFormat := w.ffmpegService.TaskOutputFormat(task)
+	outputFilename := w.ffmpegService.GenerateOutputName(task.ID, outputFormat)
+	var outputURL string
+	if w.ffmpegService.IsPackagedOutput(outputFormat) {
+		outputDir := filepath.Dir(outputPath)
+
+		// 缩略图任务的所有产物都记录下来（上传后本地目录会被删除）
+		var thumbnailFiles map[string]string
+		if outputFormat == "thumbnails" {
+			thumbnailFiles = w.ffmpegService.ThumbnailArtifacts(outputDir)
+		}
 
-		log.Printf("Task %s completed successfully, output: %s", task.ID, outputURL)
-		return nil
+		baseURL := w.uploadOutputDir(task.ID, outputDir, task.ID)
+		outputURL = baseURL + "/" + filepath.Base(outputPath)
+		for name, file := range thumbnailFiles {
+			artifacts[name] = baseURL + "/" + file
+		}
 	} else {
-		// 任务失败 - 记录详细的错误信息
-		log.Printf("Task %s failed with error: %s", task.ID, result.ErrorMessage)
-		log.Printf("Task %s - FFmpeg command: %s", task.ID, result.Command)
-		log.Printf("Task %s - Stderr log (last 500 chars): %s", task.ID, truncateString(result.StderrLog, 500))
+		outputURL = w.uploadOutput(task.ID, outputPath, outputFilename)
+	}
 
-		// 任务失败
-		w.taskService.FailTask(task.ID, result.Command, result.FilterGraph, result.StderrLog, result.ErrorMessage)
+	// 响度标准化报告：从标准化遍的日志中解析实测值和结果
+	var loudness *model.LoudnessReport
+	if task.InputParams.Loudness != nil {
+		report, err := w.ffmpegService.LoudnessReport(task.InputParams.Loudness, result.StderrLog)
+		if err != nil {
+			log.Printf("Task %s: Warning: failed to parse loudness report: %v", task.ID, err)
+		} else {
+			loudness = report
+			log.Printf("Task %s: Loudness %.2f LUFS -> %.2f LUFS (%s)", task.ID, report.MeasuredI, report.OutputI, report.NormalizationType)
+		}
+	}
 
-		// 广播失败消息
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:  task.ID,
-			Status:  model.TaskStatusFailed,
-			Message: result.ErrorMessage,
-		})
+	log.Printf("Task %s: Marking task as completed", task.ID)
+	w.taskService.CompleteTask(task.ID, service.TaskResult{
+		FFmpegCommand: result.Command,
+		FilterGraph:   result.FilterGraph,
+		StderrLog:     result.StderrLog,
+		OutputFile:    outputPath,
+		OutputURL:     outputURL,
+		TotalFrames:   totalFrames, // 传入总帧数
+		Artifacts:     artifacts,
+		Loudness:      loudness,
+		ErrorMessage:  postErr,
+	})
 
-		return fmt.Errorf("ffmpeg execution failed: %s", result.ErrorMessage)
+	// 广播完成消息（最终状态）
+	message := completedMessage(task.Type)
+	if postErr != "" {
+		message += " (" + postErr + ")"
 	}
-}
+	w.broadcastProgress(task.ID, model.TaskProgress{
+		TaskID:       task.ID,
+		Status:       model.TaskStatusCompleted,
+		Progress:     100,
+		CurrentFrame: totalFrames,
+		TotalFrames:  totalFrames,
+		Message:      message,
+	})
 
-// processImageSlideshow 处理多图片轮播视频任务
-func (w *Worker) processImageSlideshow(ctx context.Context, task *model.Task) (err error) {
-	// 添加 panic 恢复机制
-	defer func() {
-		if r := recover(); r != nil {
-			errMsg := fmt.Sprintf("Task panic: %v", r)
-			log.Printf("Task %s panic: %v", task.ID, r)
-			w.taskService.FailTask(task.ID, "", "", "", errMsg)
-			w.broadcastProgress(task.ID, model.TaskProgress{
-				TaskID:  task.ID,
-				Status:  model.TaskStatusFailed,
-				Message: errMsg,
-			})
-			err = fmt.Errorf("%s", errMsg)
-		}
-	}()
+	// 短暂延迟确保WebSocket消息发送完成
+	time.Sleep(100 * time.Millisecond)
 
-	log.Printf("Task %s: Starting image slideshow processing", task.ID)
+	log.Printf("Task %s completed successfully, output: %s", task.ID, outputURL)
+}
 
-	// 生成输出路径
-	outputPath := w.ffmpegService.GenerateOutputPath(task.ID, task.InputParams.OutputFormat)
-	log.Printf("Task %s: Output path: %s", task.ID, outputPath)
+// uploadOutput 上传输出文件，返回访问URL
+// 七牛云存储上传失败时回退到本地访问路径，不影响任务完成
+func (w *Worker) uploadOutput(taskID, outputPath, outputFilename string) string {
+	localURL := fmt.Sprintf("/api/outputs/%s", outputFilename)
+	if w.config.Storage.Type != "qiniu" || !w.config.Qiniu.Enabled {
+		return localURL
+	}
 
-	// 构建ffmpeg命令
-	args, totalFrames, tempFiles, err := w.ffmpegService.BuildImageSlideshowCommand(task.InputParams, outputPath)
+	log.Printf("Task %s: Uploading to Qiniu cloud storage", taskID)
+	// 生成七牛云存储key（使用outputs目录前缀）
+	key := fmt.Sprintf("outputs/%s", outputFilename)
+	cloudURL, err := w.storage.UploadFile(outputPath, key)
 	if err != nil {
-		errMsg := fmt.Sprintf("Failed to build ffmpeg command: %v", err)
-		log.Printf("Task %s: %s", task.ID, errMsg)
-		w.taskService.FailTask(task.ID, "", "", "", errMsg)
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:  task.ID,
-			Status:  model.TaskStatusFailed,
-			Message: errMsg,
-		})
-		return fmt.Errorf("%s", errMsg)
+		log.Printf("Task %s: Warning - failed to upload output to cloud: %v", taskID, err)
+		return localURL
 	}
 
-	// 确保临时文件在函数结束时被清理
-	defer func() {
-		if len(tempFiles) > 0 {
-			log.Printf("Task %s: Cleaning up %d temporary files", task.ID, len(tempFiles))
-			w.ffmpegService.CleanupTempFiles(tempFiles)
-		}
-	}()
+	log.Printf("Task %s: Upload success, URL: %s", taskID, cloudURL)
+	// 删除本地临时文件
+	if err := w.storage.DeleteLocalFile(outputPath); err != nil {
+		log.Printf("Task %s: Warning - failed to delete local output file %s: %v", taskID, outputPath, err)
+	}
+	return cloudURL
+}
 
-	log.Printf("Task %s: Total frames: %d, Images: %d, Command: ffmpeg %v", task.ID, totalFrames, len(task.InputParams.ImagePaths), args)
+// uploadOutputDir 上传目录形式的输出（清单/主播放列表+切片、缩略图等），返回目录的访问URL前缀
+// dirName为目录相对输出根目录的名称，同时作为云存储key前缀 outputs/dirName
+func (w *Worker) uploadOutputDir(taskID, outputDir, dirName string) string {
+	localURL := fmt.Sprintf("/api/outputs/%s", dirName)
+	if w.config.Storage.Type != "qiniu" || !w.config.Qiniu.Enabled {
+		return localURL
+	}
 
-	// 创建进度回调
-	progressCallback := func(progress ffmpeg.Progress) {
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     progress.Progress,
-			CurrentFrame: progress.Frame,
-			TotalFrames:  totalFrames,
-			ETA:          progress.ETA,
-			Message:      fmt.Sprintf("Processing slideshow: %.1f%% (Frame %d/%d, Speed: %.2fx)", progress.Progress, progress.Frame, totalFrames, progress.Speed),
-		})
+	log.Printf("Task %s: Uploading output directory %s to Qiniu cloud storage", taskID, outputDir)
+	keyPrefix := fmt.Sprintf("outputs/%s", dirName)
+	if err := w.storage.UploadDir(outputDir, keyPrefix); err != nil {
+		log.Printf("Task %s: Warning - failed to upload output directory to cloud: %v", taskID, err)
+		return localURL
+	}
 
-		w.taskService.UpdateTaskProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     progress.Progress,
-			CurrentFrame: progress.Frame,
-			TotalFrames:  totalFrames,
-			ETA:          progress.ETA,
-		})
+	cloudURL := w.storage.GetPublicURL(keyPrefix)
+	log.Printf("Task %s: Upload success, URL prefix: %s", taskID, cloudURL)
+	// 删除本地输出目录
+	if err := w.storage.DeleteLocalDir(outputDir); err != nil {
+		log.Printf("Task %s: Warning - failed to delete local output dir %s: %v", taskID, outputDir, err)
 	}
+	return cloudURL
+}
 
-	// 执行ffmpeg命令
-	log.Printf("Task %s: Starting ffmpeg execution", task.ID)
-	result := w.ffmpegService.ExecuteWithProgress(ctx, args, totalFrames, progressCallback)
-	log.Printf("Task %s: FFmpeg execution finished, success: %v", task.ID, result.Success)
+// generateThumbnails 缩略图附加步骤：基于任务输出生成封面、雪碧图和WebVTT并上传
+// 返回的错误由调用方记录，不影响主任务完成
+func (w *Worker) generateThumbnails(ctx context.Context, taskID string, inputPath string, opts *model.ThumbnailOptions, artifacts map[string]string) error {
+	dirName := taskID + "_thumbnails"
+	outputDir := filepath.Join(w.config.Storage.OutputDir, dirName)
 
-	if result.Success {
-		// 任务成功 - 强制广播100%进度
-		log.Printf("Task %s: FFmpeg succeeded, broadcasting final progress", task.ID)
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			ETA:          0,
-			Message:      "Processing completed, finalizing...",
-		})
+	args, totalFrames, err := w.ffmpegService.BuildThumbnailPostCommand(inputPath, opts, outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to build thumbnail command: %w", err)
+	}
 
-		w.taskService.UpdateTaskProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusProcessing,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			ETA:          0,
-		})
+	w.broadcastProgress(taskID, model.TaskProgress{
+		TaskID:   taskID,
+		Status:   model.TaskStatusProcessing,
+		Progress: 100,
+		Message:  "Generating thumbnails...",
+	})
 
-		// 生成输出文件URL
-		outputURL := ""
-		outputFilename := w.ffmpegService.GenerateOutputName(task.ID, task.InputParams.OutputFormat)
-
-		if w.config.Storage.Type == "qiniu" && w.config.Qiniu.Enabled {
-			log.Printf("Task %s: Uploading to Qiniu cloud storage", task.ID)
-			key := fmt.Sprintf("outputs/%s", outputFilename)
-			cloudURL, err := w.storage.UploadFile(outputPath, key)
-			if err != nil {
-				log.Printf("Task %s: Warning - failed to upload output to cloud: %v", task.ID, err)
-				outputURL = fmt.Sprintf("/api/outputs/%s", outputFilename)
-			} else {
-				log.Printf("Task %s: Upload success, URL: %s", task.ID, cloudURL)
-				outputURL = cloudURL
-				if err := w.storage.DeleteLocalFile(outputPath); err != nil {
-					log.Printf("Task %s: Warning - failed to delete local output file %s: %v", task.ID, outputPath, err)
-				}
-			}
-		} else {
-			outputURL = fmt.Sprintf("/api/outputs/%s", outputFilename)
-		}
+	result := w.ffmpegService.ExecuteWithProgress(ctx, args, totalFrames, nil)
+	if !result.Success {
+		return fmt.Errorf("thumbnail generation failed: %s", result.ErrorMessage)
+	}
 
-		// 任务成功
-		log.Printf("Task %s: Marking task as completed", task.ID)
-		w.taskService.CompleteTask(task.ID, service.TaskResult{
-			FFmpegCommand: result.Command,
-			FilterGraph:   result.FilterGraph,
-			StderrLog:     result.StderrLog,
-			OutputFile:    outputPath,
-			OutputURL:     outputURL,
-			TotalFrames:   totalFrames,
-		})
+	files := w.ffmpegService.ThumbnailArtifacts(outputDir)
+	baseURL := w.uploadOutputDir(taskID, outputDir, dirName)
+	for name, file := range files {
+		artifacts[name] = baseURL + "/" + file
+	}
+	return nil
+}
 
-		// 广播完成消息
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:       task.ID,
-			Status:       model.TaskStatusCompleted,
-			Progress:     100,
-			CurrentFrame: totalFrames,
-			TotalFrames:  totalFrames,
-			Message:      "Slideshow video completed successfully",
-		})
+// failExecution ffmpeg执行失败 - 记录详细的错误信息
+func (w *Worker) failExecution(taskID string, result *ffmpeg.ExecuteResult) error {
+	log.Printf("Task %s failed with error: %s", taskID, result.ErrorMessage)
+	log.Printf("Task %s - FFmpeg command: %s", taskID, result.Command)
+	log.Printf("Task %s - Stderr log (last 500 chars): %s", taskID, truncateString(result.StderrLog, 500))
 
-		time.Sleep(100 * time.Millisecond)
-		log.Printf("Task %s completed successfully, output: %s", task.ID, outputURL)
-		return nil
-	} else {
-		// 任务失败
-		log.Printf("Task %s failed with error: %s", task.ID, result.ErrorMessage)
-		w.taskService.FailTask(task.ID, result.Command, result.FilterGraph, result.StderrLog, result.ErrorMessage)
-		w.broadcastProgress(task.ID, model.TaskProgress{
-			TaskID:  task.ID,
-			Status:  model.TaskStatusFailed,
-			Message: result.ErrorMessage,
-		})
-		return fmt.Errorf("ffmpeg execution failed: %s", result.ErrorMessage)
-	}
+	w.taskService.FailTask(taskID, result.Command, result.FilterGraph, result.StderrLog, result.ErrorMessage)
+	w.broadcastProgress(taskID, model.TaskProgress{
+		TaskID:  taskID,
+		Status:  model.TaskStatusFailed,
+		Message: result.ErrorMessage,
+	})
+
+	return fmt.Errorf("ffmpeg execution failed: %s", result.ErrorMessage)
+}
+
+// failTask 任务在执行ffmpeg前失败（参数错误、下载失败、panic等）
+func (w *Worker) failTask(taskID string, errMsg string) error {
+	log.Printf("Task %s: %s", taskID, errMsg)
+	w.taskService.FailTask(taskID, "", "", "", errMsg)
+	w.broadcastProgress(taskID, model.TaskProgress{
+		TaskID:  taskID,
+		Status:  model.TaskStatusFailed,
+		Message: errMsg,
+	})
+	return fmt.Errorf("%s", errMsg)
 }
 
 // truncateString 截断字符串到指定长度
@@ -42,6 +42,11 @@ export const taskAPI = {
     return request.get('/tasks', { params })
   },
 
+  // 获取支持的转场效果列表
+  listTransitions() {
+    return request.get('/transitions')
+  },
+
   // WebSocket连接（实时进度）
   connectProgress(taskId) {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'